@@ -0,0 +1,43 @@
+package graphql
+
+// Appender is implemented by custom collection types that GraphQL lists
+// are decoded into, as an alternative to slices and arrays. The list is
+// decoded into the zero value of the collection, one element at a time.
+//
+// AppendGraphQL appends a new zero element to the collection, and returns
+// a non-nil pointer to it, into which the element is decoded. The pointer
+// must stay valid until the next call. When constructing queries,
+// AppendGraphQL is called on the zero value to find out the element type.
+//
+// For example, a collection that keeps pointers to its elements:
+//
+//	type Issues struct {
+//		list []*Issue
+//	}
+//
+//	func (c *Issues) AppendGraphQL() any {
+//		issue := new(Issue)
+//		c.list = append(c.list, issue)
+//		return issue
+//	}
+type Appender interface {
+	AppendGraphQL() any
+}
+
+// Ranger is implemented by custom collection types that implement Appender,
+// so that Marshal can encode them as lists. Marshal returns an error for
+// collections that don't implement it.
+//
+// RangeGraphQL calls fn with each element of the collection, in order,
+// until fn returns false. For example:
+//
+//	func (c *Issues) RangeGraphQL(fn func(elem any) bool) {
+//		for _, issue := range c.list {
+//			if !fn(issue) {
+//				return
+//			}
+//		}
+//	}
+type Ranger interface {
+	RangeGraphQL(fn func(elem any) bool)
+}
@@ -0,0 +1,464 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// Enum is a GraphQL enum value, such as "OPEN". When used in arguments
+// set by FieldArguments, it's written without quotes, unlike strings.
+type Enum string
+
+// Variable is a reference to a GraphQL variable by name, e.g., "id" for $id.
+// When used in arguments set by FieldArguments or Selection.Args,
+// it's written as the variable, e.g., $id.
+type Variable string
+
+// FieldArguments sets arguments of the field at path, which is a dot-separated
+// list of GraphQL field names (or aliases) leading to the field from the root,
+// e.g., "repository.issue". Lists are transparent, so the field inside nodes
+// of a connection is at path "search.nodes.commits", for example. Fields in
+// named fragment definitions are at paths that start with "..." followed by
+// the fragment name, e.g., "...UserFields.avatarUrl".
+//
+// The argument values are Go values that get written as GraphQL literals:
+// nil as null, booleans, numbers, strings (escaped), Enum values (unquoted),
+// slices and arrays as lists, and maps with string keys and structs as input
+// objects. Struct fields use the names in their json struct tags, if any.
+// Values that implement json.Marshaler are written as their JSON encoding,
+// and values of custom scalars registered with CustomScalar are written as
+// the JSON encoding of the value returned by its encode function.
+//
+// Arguments set by FieldArguments are added to the ones in the field's
+// graphql struct tag. It's an error to specify the same argument in both.
+func FieldArguments(path string, args map[string]any) Option {
+	return func(o *options) {
+		if o.arguments == nil {
+			o.arguments = make(map[string]map[string]any)
+		}
+		if o.arguments[path] == nil {
+			o.arguments[path] = make(map[string]any)
+		}
+		for name, v := range args {
+			o.arguments[path][name] = v
+		}
+	}
+}
+
+// withArguments returns a copy of field sel with arguments args
+// (as set by FieldArguments) added to it. Values of custom scalars
+// in scalars are encoded by them.
+func withArguments(sel *language.Field, args map[string]any, scalars scalars) (*language.Field, error) {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f := *sel
+	f.Arguments = append([]*language.Argument(nil), sel.Arguments...)
+	for _, name := range names {
+		if !language.IsName(name) {
+			return nil, fmt.Errorf("invalid argument name %q", name)
+		}
+		for _, a := range sel.Arguments {
+			if a.Name == name {
+				return nil, fmt.Errorf("argument %q is specified both in graphql struct tag and FieldArguments", name)
+			}
+		}
+		v, err := argumentValue(reflect.ValueOf(args[name]), scalars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %v", name, err)
+		}
+		f.Arguments = append(f.Arguments, &language.Argument{Name: name, Value: v})
+	}
+	return &f, nil
+}
+
+var (
+	enumType      = reflect.TypeOf(Enum(""))
+	variableType  = reflect.TypeOf(Variable(""))
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// argumentValue converts the Go value v to a GraphQL input value.
+// Values of custom scalars in scalars are encoded by them.
+func argumentValue(v reflect.Value, scalars scalars) (language.Value, error) {
+	if !v.IsValid() {
+		return &language.NullValue{}, nil
+	}
+	if s, ok := scalars[v.Type()]; ok && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		j, err := s.encode(v)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		return encodedValue(b)
+	}
+	if v.Type() == enumType {
+		if name := v.String(); !language.IsName(name) || name == "true" || name == "false" || name == "null" {
+			return nil, fmt.Errorf("invalid enum value %q", name)
+		}
+		return &language.EnumValue{Name: v.String()}, nil
+	}
+	if v.Type() == variableType {
+		if name := v.String(); !language.IsName(name) {
+			return nil, fmt.Errorf("invalid variable name %q", name)
+		}
+		return &language.Variable{Name: v.String()}, nil
+	}
+	if v.Type().Implements(jsonMarshaler) && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		b, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return encodedValue(b)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &language.NullValue{}, nil
+		}
+		return argumentValue(v.Elem(), scalars)
+	case reflect.Bool:
+		return &language.BooleanValue{Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &language.IntValue{Raw: strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &language.IntValue{Raw: strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("unsupported float value %v", f)
+		}
+		return &language.FloatValue{Raw: strconv.FormatFloat(f, 'g', -1, v.Type().Bits())}, nil
+	case reflect.String:
+		return &language.StringValue{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &language.NullValue{}, nil
+		}
+		l := &language.ListValue{Values: make([]language.Value, v.Len())}
+		for i := range l.Values {
+			var err error
+			l.Values[i], err = argumentValue(v.Index(i), scalars)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %v", v.Type().Key())
+		}
+		if v.IsNil() {
+			return &language.NullValue{}, nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		o := &language.ObjectValue{}
+		for _, k := range keys {
+			fv, err := argumentValue(v.MapIndex(k), scalars)
+			if err != nil {
+				return nil, err
+			}
+			o.Fields = append(o.Fields, &language.ObjectField{Name: k.String(), Value: fv})
+		}
+		return o, nil
+	case reflect.Struct:
+		o := &language.ObjectValue{}
+		err := appendObjectFields(o, v, scalars)
+		return o, err
+	default:
+		return nil, fmt.Errorf("unsupported type %v", v.Type())
+	}
+}
+
+// appendObjectFields appends the fields of struct v to input object o,
+// following the naming rules of json struct tags.
+func appendObjectFields(o *language.ObjectValue, v reflect.Value, scalars scalars) error {
+	return objectFields(v, func(sf reflect.StructField, name string, fv reflect.Value) error {
+		value, err := argumentValue(fv, scalars)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", sf.Name, err)
+		}
+		o.Fields = append(o.Fields, &language.ObjectField{Name: name, Value: value})
+		return nil
+	})
+}
+
+// objectFields calls fn with each field of struct v that's a field of
+// an input object, along with its name, following the naming rules of
+// json struct tags. Fields of embedded structs are promoted.
+func objectFields(v reflect.Value, fn func(sf reflect.StructField, name string, fv reflect.Value) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		tag, hasTag := sf.Tag.Lookup("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		switch {
+		case tag == "-":
+			continue
+		case sf.Anonymous && sf.Type.Kind() == reflect.Struct && name == "":
+			// Fields of embedded struct are promoted to parent struct.
+			err := objectFields(v.Field(i), fn)
+			if err != nil {
+				return err
+			}
+			continue
+		case sf.PkgPath != "":
+			// Skip unexported field.
+			continue
+		}
+		if !hasTag || name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(v.Field(i)) {
+			continue
+		}
+		err := fn(sf, name, v.Field(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyValue reports whether v is empty, as defined by the omitempty json struct tag option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero() && v.Kind() != reflect.Struct
+}
+
+// encodedValue converts the JSON encoding b to a GraphQL input value.
+func encodedValue(b []byte) (language.Value, error) {
+	var j any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err := dec.Decode(&j)
+	if err != nil {
+		return nil, err
+	}
+	return jsonValue(j), nil
+}
+
+// jsonValue converts the JSON value j, as decoded into an any
+// with json.Decoder.UseNumber, to a GraphQL input value.
+func jsonValue(j any) language.Value {
+	switch j := j.(type) {
+	case nil:
+		return &language.NullValue{}
+	case bool:
+		return &language.BooleanValue{Value: j}
+	case json.Number:
+		if _, err := strconv.ParseInt(string(j), 10, 64); err == nil {
+			return &language.IntValue{Raw: string(j)}
+		}
+		return &language.FloatValue{Raw: string(j)}
+	case string:
+		return &language.StringValue{Value: j}
+	case []any:
+		l := &language.ListValue{Values: make([]language.Value, len(j))}
+		for i := range j {
+			l.Values[i] = jsonValue(j[i])
+		}
+		return l
+	case map[string]any:
+		keys := make([]string, 0, len(j))
+		for k := range j {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		o := &language.ObjectValue{}
+		for _, k := range keys {
+			o.Fields = append(o.Fields, &language.ObjectField{Name: k, Value: jsonValue(j[k])})
+		}
+		return o
+	default:
+		panic(fmt.Errorf("unexpected JSON value type %T", j))
+	}
+}
+
+// HoistArguments makes field arguments be sent as variables, rather than
+// written as literals in the document, so that the document stays the same
+// regardless of argument values. That lets servers cache query plans and
+// makes documents suitable for persisted queries.
+//
+// types maps the arguments to hoist to their GraphQL types, e.g., "String!".
+// Arguments are identified by the path of their field, as in FieldArguments,
+// followed by a dot and the argument name, e.g., "repository.owner".
+// Both arguments in graphql struct tags and ones set by FieldArguments can be
+// hoisted. Hoisted variables are named $v1, $v2 and so on, in document order.
+// Arguments that aren't in types are left as is.
+func HoistArguments(types map[string]string) Option {
+	return func(o *options) {
+		if o.hoist == nil {
+			o.hoist = make(map[string]string)
+		}
+		for arg, typ := range types {
+			o.hoist[arg] = typ
+		}
+	}
+}
+
+// hoistKey returns a string that identifies the arguments set by HoistArguments.
+func (opts options) hoistKey() string {
+	if len(opts.hoist) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(opts.hoist))
+	for arg := range opts.hoist {
+		keys = append(keys, arg)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, arg := range keys {
+		sb.WriteString(arg)
+		sb.WriteString("=")
+		sb.WriteString(opts.hoist[arg])
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// setHoist sets up qw to hoist arguments with types, as set by HoistArguments.
+func (qw *queryWriter) setHoist(types map[string]string) error {
+	if len(types) == 0 {
+		return nil
+	}
+	qw.hoist = make(map[string]language.Type, len(types))
+	qw.hoistFields = make(map[string]bool)
+	for arg, typ := range types {
+		path, _, ok := cutLast(arg, ".")
+		if !ok {
+			return fmt.Errorf("HoistArguments argument %q must be a field path followed by a dot and the argument name", arg)
+		}
+		t, err := language.ParseType(typ)
+		if err != nil {
+			return fmt.Errorf("HoistArguments argument %q has invalid type %q: %v", arg, typ, err)
+		}
+		qw.hoist[arg] = t
+		qw.hoistFields[path] = true
+	}
+	qw.hoistMatched = make(map[string]bool)
+	return nil
+}
+
+// hoistArguments returns a copy of field sel, at responsePath, with its arguments
+// that are set to be hoisted replaced by variables. args are the arguments
+// set by FieldArguments, whose Go values are used as variable values as is.
+func (qw *queryWriter) hoistArguments(sel *language.Field, responsePath string, args map[string]any) (*language.Field, error) {
+	f := *sel
+	f.Arguments = make([]*language.Argument, len(sel.Arguments))
+	for i, a := range sel.Arguments {
+		key := responsePath + "." + a.Name
+		t, ok := qw.hoist[key]
+		if !ok {
+			f.Arguments[i] = a
+			continue
+		}
+		qw.hoistMatched[key] = true
+		if _, ok := a.Value.(*language.Variable); ok {
+			// Already a variable, nothing to hoist.
+			f.Arguments[i] = a
+			continue
+		}
+		var value any
+		if v, ok := args[a.Name]; ok {
+			value = v
+		} else {
+			var err error
+			value, err = literalValue(a.Value)
+			if err != nil {
+				return nil, fmt.Errorf("can't hoist argument %q: %v", a.Name, err)
+			}
+		}
+		name := qw.nextVariable()
+		if qw.hoisted == nil {
+			qw.hoisted = make(map[string]any)
+			qw.hoistedTypes = make(map[string]language.Type)
+		}
+		qw.hoisted[name] = value
+		qw.hoistedTypes[name] = t
+		f.Arguments[i] = &language.Argument{Name: a.Name, Value: &language.Variable{Name: name}}
+	}
+	return &f, nil
+}
+
+// nextVariable returns the name of the next variable to hoist an argument into.
+func (qw *queryWriter) nextVariable() string {
+	for i := len(qw.hoisted) + 1; ; i++ {
+		name := "v" + strconv.Itoa(i)
+		if _, ok := qw.variables[name]; !ok {
+			if _, ok := qw.hoisted[name]; !ok {
+				return name
+			}
+		}
+	}
+}
+
+// literalValue converts the GraphQL input value v, which must not contain
+// variables, to a Go value that encodes to it in JSON.
+func literalValue(v language.Value) (any, error) {
+	switch v := v.(type) {
+	case *language.Variable:
+		return nil, fmt.Errorf("value contains variable $%s", v.Name)
+	case *language.IntValue:
+		return json.Number(v.Raw), nil
+	case *language.FloatValue:
+		return json.Number(v.Raw), nil
+	case *language.StringValue:
+		return v.Value, nil
+	case *language.BooleanValue:
+		return v.Value, nil
+	case *language.NullValue:
+		return nil, nil
+	case *language.EnumValue:
+		return v.Name, nil
+	case *language.ListValue:
+		l := make([]any, len(v.Values))
+		for i := range v.Values {
+			var err error
+			l[i], err = literalValue(v.Values[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case *language.ObjectValue:
+		o := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			var err error
+			o[f.Name], err = literalValue(f.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	default:
+		panic(fmt.Errorf("unexpected value type %T", v))
+	}
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i != -1 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
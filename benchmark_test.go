@@ -0,0 +1,70 @@
+package graphql
+
+import "testing"
+
+type benchmarkQuery struct {
+	Repository struct {
+		Issue struct {
+			Author         actor
+			PublishedAt    DateTime
+			LastEditedAt   *DateTime
+			Editor         *actor
+			Body           String
+			ReactionGroups []struct {
+				Content ReactionContent
+				Users   struct {
+					TotalCount Int
+				}
+				ViewerHasReacted Boolean
+			}
+			Comments struct {
+				Nodes []struct {
+					DatabaseID Int
+					Author     actor
+					Body       String
+				}
+				PageInfo struct {
+					EndCursor   String
+					HasNextPage Boolean
+				}
+			} `graphql:"comments(first:$commentsFirst)"`
+		} `graphql:"issue(number:$issueNumber)"`
+	} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+}
+
+type actor struct {
+	Login     String
+	AvatarURL URI `graphql:"avatarUrl(size:72)"`
+	URL       URI
+}
+
+var benchmarkVariables = map[string]any{
+	"repositoryOwner": String("shurcooL-test"),
+	"repositoryName":  String("test-repo"),
+	"issueNumber":     Int(1),
+	"commentsFirst":   Int(10),
+}
+
+func BenchmarkConstructQuery(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, err := constructQuery(&benchmarkQuery{}, benchmarkVariables)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConstructQuery_queryWriter measures constructing a query
+// without the use of queryCache, for comparison. Struct field metadata
+// is still cached by fields.Of, so it measures a queryCache miss
+// for a type whose fields have been used before.
+func BenchmarkConstructQuery_queryWriter(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		qw := queryWriter{variables: benchmarkVariables}
+		err := qw.query(&benchmarkQuery{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = "query(" + queryArguments(benchmarkVariables, nil) + ")" + qw.buf.String()
+	}
+}
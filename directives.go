@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// checkSelection checks the directives of selection sel, parsed from
+// a graphql struct tag, and that the variables used in their arguments
+// are provided. Variables used in field arguments aren't checked.
+//
+// The @include and @skip directives must have a single "if" argument,
+// and can't be repeated. Other directives are passed through as is.
+func checkSelection(sel language.Selection, variables map[string]any) error {
+	var directives []*language.Directive
+	switch sel := sel.(type) {
+	case *language.Field:
+		directives = sel.Directives
+	case *language.InlineFragment:
+		directives = sel.Directives
+	}
+	seen := make(map[string]bool)
+	for _, d := range directives {
+		if d.Name != "include" && d.Name != "skip" {
+			continue
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("directive @%s can't be used more than once", d.Name)
+		}
+		seen[d.Name] = true
+		if len(d.Arguments) != 1 || d.Arguments[0].Name != "if" {
+			return fmt.Errorf("directive @%s must have a single \"if\" argument", d.Name)
+		}
+	}
+	for _, d := range directives {
+		for _, a := range d.Arguments {
+			err := checkVariables(a.Value, variables)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkVariables checks that the variables used in value v are provided.
+func checkVariables(v language.Value, variables map[string]any) error {
+	switch v := v.(type) {
+	case *language.Variable:
+		if _, ok := variables[v.Name]; !ok {
+			return fmt.Errorf("variable $%s is used, but not provided in variables", v.Name)
+		}
+	case *language.ListValue:
+		for _, v := range v.Values {
+			err := checkVariables(v, variables)
+			if err != nil {
+				return err
+			}
+		}
+	case *language.ObjectValue:
+		for _, f := range v.Fields {
+			err := checkVariables(f.Value, variables)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// Selection is a field or inline fragment of a query that's built at run time,
+// rather than derived from a struct. Use Select and On to create one.
+// The methods that customize a selection return a modified copy of it.
+type Selection struct {
+	alias         string
+	name          string
+	arguments     map[string]any
+	typeCondition string // Type condition of inline fragment created with On.
+	selections    Selections
+}
+
+// Selections is a selection set built at run time. It can be used instead of
+// a struct with ConstructQuery, ConstructMutation, Client.QuerySelections and
+// Client.MutateSelections. For example:
+//
+//	q := graphql.Selections{
+//		graphql.Select("viewer",
+//			graphql.Select("login"),
+//			graphql.Select("createdAt"),
+//		),
+//		graphql.Select("repository",
+//			graphql.Select("description"),
+//		).Args(map[string]any{"owner": "octocat", "name": graphql.Variable("name")}),
+//	}
+//
+// Documents constructed from selections are never cached.
+type Selections []*Selection
+
+// Select returns a selection of field name, with the provided
+// selections as its selection set. A field of scalar type has none.
+func Select(name string, selections ...*Selection) *Selection {
+	return &Selection{name: name, selections: selections}
+}
+
+// On returns an inline fragment with type condition typeCondition,
+// such as "... on User{login}", with the provided selections.
+func On(typeCondition string, selections ...*Selection) *Selection {
+	return &Selection{typeCondition: typeCondition, selections: selections}
+}
+
+// As returns a copy of field selection s with alias alias.
+// The field's value is found under the alias in the response.
+func (s *Selection) As(alias string) *Selection {
+	c := *s
+	c.alias = alias
+	return &c
+}
+
+// Args returns a copy of field selection s with arguments args added to it.
+// Argument values are written the same way as the ones set by FieldArguments.
+// Use Variable to refer to a variable.
+func (s *Selection) Args(args map[string]any) *Selection {
+	c := *s
+	c.arguments = make(map[string]any, len(s.arguments)+len(args))
+	for name, value := range s.arguments {
+		c.arguments[name] = value
+	}
+	for name, value := range args {
+		c.arguments[name] = value
+	}
+	return &c
+}
+
+// responseKey returns the key of field selection s in the response.
+func (s *Selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// writeSelections writes the selection set sels. responsePath is
+// the GraphQL response key path of the field that sels belong to.
+func (qw *queryWriter) writeSelections(sels Selections, responsePath string) error {
+	if len(sels) == 0 {
+		return selectionError(responsePath, "selection set can't be empty")
+	}
+	qw.openSelectionSet()
+	for _, s := range sels {
+		if s == nil {
+			return selectionError(responsePath, "selection is nil")
+		}
+		qw.startSelection()
+		if s.name == "" {
+			// An inline fragment created with On.
+			if !language.IsName(s.typeCondition) {
+				return selectionError(responsePath, "invalid type condition %q", s.typeCondition)
+			}
+			language.WriteSelection(&qw.buf, &language.InlineFragment{TypeCondition: s.typeCondition}, qw.indent != "")
+			err := qw.writeSelections(s.selections, responsePath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !language.IsName(s.name) {
+			return selectionError(responsePath, "invalid field name %q", s.name)
+		}
+		if s.alias != "" && !language.IsName(s.alias) {
+			return selectionError(responsePath, "invalid alias %q of field %q", s.alias, s.name)
+		}
+		fieldResponsePath := join(responsePath, s.responseKey())
+		sel := &language.Field{Alias: s.alias, Name: s.name}
+		var err error
+		if len(s.arguments) > 0 {
+			sel, err = withArguments(sel, s.arguments, qw.scalars)
+			if err != nil {
+				return selectionError(fieldResponsePath, "%v", err)
+			}
+		}
+		sel, err = qw.applyArguments(sel, fieldResponsePath)
+		if err != nil {
+			return selectionError(fieldResponsePath, "%v", err)
+		}
+		language.WriteSelection(&qw.buf, sel, qw.indent != "")
+		if len(s.selections) > 0 {
+			err := qw.writeSelections(s.selections, fieldResponsePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	qw.closeSelectionSet()
+	return nil
+}
+
+// selectionError returns an error about the selection at response path.
+func selectionError(path string, format string, a ...any) error {
+	if path == "" {
+		return fmt.Errorf(format, a...)
+	}
+	return fmt.Errorf("selection %s: %s", path, fmt.Sprintf(format, a...))
+}
+
+// QuerySelections executes a single GraphQL query request,
+// with a query constructed from sels, and returns the response data.
+//
+// If the response has both data and errors, the data is returned
+// along with the errors.
+func (c *Client) QuerySelections(ctx context.Context, sels Selections, variables map[string]any, opts ...Option) (Result, error) {
+	return c.doSelections(ctx, queryOperation, sels, variables, c.options(opts))
+}
+
+// MutateSelections executes a single GraphQL mutation request,
+// with a mutation constructed from sels, and returns the response data.
+// See QuerySelections for details.
+func (c *Client) MutateSelections(ctx context.Context, sels Selections, variables map[string]any, opts ...Option) (Result, error) {
+	return c.doSelections(ctx, mutationOperation, sels, variables, c.options(opts))
+}
+
+// doSelections executes a single GraphQL operation constructed from sels.
+func (c *Client) doSelections(ctx context.Context, op operationType, sels Selections, variables map[string]any, opts options) (Result, error) {
+	o, err := construct(op, sels, variables, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	var r Result
+	err = c.exec(ctx, o.query, &r, o.variables(variables), opts)
+	return r, err
+}
+
+// Result is a JSON value from response data, such as the data itself,
+// that's navigated without declaring Go types for it. The zero Result
+// is a value that doesn't exist.
+//
+// Getting a missing key or index returns a Result that doesn't exist,
+// so lookups can be chained, e.g.:
+//
+//	login, ok := r.Get("viewer").Get("login").AsString()
+type Result struct {
+	value  any // Decoded by encoding/json with UseNumber.
+	exists bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Result) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	*r = Result{value: v, exists: true}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.value)
+}
+
+// Get returns the value of key in object r.
+func (r Result) Get(key string) Result {
+	m, ok := r.value.(map[string]any)
+	if !ok {
+		return Result{}
+	}
+	v, ok := m[key]
+	return Result{value: v, exists: ok}
+}
+
+// Index returns the element at index i of list r.
+func (r Result) Index(i int) Result {
+	l, ok := r.value.([]any)
+	if !ok || i < 0 || i >= len(l) {
+		return Result{}
+	}
+	return Result{value: l[i], exists: true}
+}
+
+// Len returns the number of elements of list r, or 0 if r isn't a list.
+func (r Result) Len() int {
+	l, _ := r.value.([]any)
+	return len(l)
+}
+
+// Keys returns the keys of object r, sorted, or nil if r isn't an object.
+func (r Result) Keys() []string {
+	m, ok := r.value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Exists reports whether r exists.
+func (r Result) Exists() bool { return r.exists }
+
+// IsNull reports whether r exists and is null.
+func (r Result) IsNull() bool { return r.exists && r.value == nil }
+
+// Value returns r as decoded by encoding/json, with numbers as json.Number.
+// Objects are map[string]any, and lists are []any.
+// It returns nil if r doesn't exist.
+func (r Result) Value() any { return r.value }
+
+// AsString returns r as a string, and reports whether it's one.
+func (r Result) AsString() (string, bool) {
+	s, ok := r.value.(string)
+	return s, ok
+}
+
+// AsInt returns r as an integer, and reports whether it's one.
+func (r Result) AsInt() (int64, bool) {
+	n, ok := r.value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	return i, err == nil
+}
+
+// AsFloat returns r as a floating-point number, and reports whether it's a number.
+func (r Result) AsFloat() (float64, bool) {
+	n, ok := r.value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// AsBool returns r as a boolean, and reports whether it's one.
+func (r Result) AsBool() (bool, bool) {
+	b, ok := r.value.(bool)
+	return b, ok
+}
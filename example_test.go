@@ -0,0 +1,51 @@
+package graphql_test
+
+import (
+	"fmt"
+
+	"github.com/shurcooL/graphql"
+)
+
+func ExampleConstructQuery() {
+	var q struct {
+		Hero struct {
+			Name  graphql.String
+			Droid struct {
+				PrimaryFunction graphql.String
+			} `graphql:"... on Droid"`
+			Human struct {
+				Height graphql.Float `graphql:"height(unit: METER)"`
+			} `graphql:"... on Human"`
+		} `graphql:"hero(episode: $ep)"`
+	}
+	variables := map[string]any{
+		"ep": graphql.String("JEDI"),
+	}
+
+	query, err := graphql.ConstructQuery(&q, variables)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(query)
+
+	query, err = graphql.ConstructQuery(&q, variables, graphql.Indent("  "))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(query)
+
+	// Output:
+	// query($ep:String!){hero(episode:$ep){__typename,name,... on Droid{primaryFunction},... on Human{height(unit:METER)}}}
+	// query ($ep: String!) {
+	//   hero(episode: $ep) {
+	//     __typename
+	//     name
+	//     ... on Droid {
+	//       primaryFunction
+	//     }
+	//     ... on Human {
+	//       height(unit: METER)
+	//     }
+	//   }
+	// }
+}
@@ -0,0 +1,139 @@
+package graphql
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/shurcooL/graphql/internal/fields"
+)
+
+// NamedFragment is implemented by struct types that correspond to GraphQL
+// named fragments. GraphQLFragment returns the fragment name and its type
+// condition, e.g., "UserFields on User". It's called on the zero value.
+//
+// Wherever a named fragment type is used in a query, either as the type of
+// a field or as an embedded struct, a fragment spread such as "...UserFields"
+// is written instead of its fields, and a single fragment definition such as
+// "fragment UserFields on User{login,name}" is written after the operation.
+// The response is decoded into every usage as if the fields were inlined.
+//
+// An embedded named fragment applies only to objects of the type
+// in its type condition, like an inline fragment with one.
+//
+// A struct type that embeds a named fragment type isn't a named fragment
+// itself, even though the GraphQLFragment method is promoted to it.
+type NamedFragment interface {
+	GraphQLFragment() string
+}
+
+// fragmentDefinition is a named fragment used in a query.
+type fragmentDefinition struct {
+	name          string
+	typeCondition string
+	t             reflect.Type // Struct type of the fragment.
+}
+
+// writeFragmentSpread writes a spread of the named fragment t, within
+// a selection set of its own unless inline is true. It records the fragment
+// so that writeFragmentDefinitions writes its definition.
+func (qw *queryWriter) writeFragmentSpread(t reflect.Type, path string, inline bool) error {
+	name, err := qw.fragment(t, path)
+	if err != nil {
+		return err
+	}
+	if !inline {
+		qw.openSelectionSet()
+	}
+	qw.startSelection()
+	io.WriteString(&qw.buf, "...")
+	io.WriteString(&qw.buf, name)
+	if !inline {
+		qw.closeSelectionSet()
+	}
+	return nil
+}
+
+// fragment returns the name of the named fragment t,
+// recording its definition the first time it's used.
+func (qw *queryWriter) fragment(t reflect.Type, path string) (string, error) {
+	name, typeCondition, err := fields.NamedFragment(t)
+	if err != nil {
+		return "", fieldError(path, "%v", err)
+	}
+	for _, d := range qw.fragments {
+		if d.name != name {
+			continue
+		}
+		if d.t != t {
+			return "", fieldError(path, "named fragment %q is used by both %v and %v", name, d.t, t)
+		}
+		return name, nil
+	}
+	qw.fragments = append(qw.fragments, fragmentDefinition{name: name, typeCondition: typeCondition, t: t})
+	return name, nil
+}
+
+// writeFragmentDefinitions writes definitions of the named fragments used
+// in the query, in order of first use. Fields inside a definition are at
+// response paths starting with "...Name", such as "...UserFields.login".
+func (qw *queryWriter) writeFragmentDefinitions() error {
+	// Definitions can use more named fragments, so qw.fragments may grow.
+	for i := 0; i < len(qw.fragments); i++ {
+		d := qw.fragments[i]
+		if qw.indent != "" {
+			io.WriteString(&qw.buf, "\n\n")
+		}
+		fmt.Fprintf(&qw.buf, "fragment %s on %s", d.name, d.typeCondition)
+		if qw.indent != "" {
+			io.WriteString(&qw.buf, " ")
+		}
+		err := qw.writeSelectionSet(d.t, d.t.Name(), "..."+d.name, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Typename returns the name of the concrete type of the object that v,
+// a struct or pointer to struct, was populated from. It's the value of
+// the struct field of v with `graphql:"__typename"` tag, which may be
+// in an embedded struct. Typename returns the empty string if v has no
+// such field, or if the field wasn't populated.
+//
+// When a selection set has inline fragments with type conditions,
+// __typename is requested automatically in order to populate only the
+// matching ones, but it's kept only if v has a field for it. Declare one,
+// such as a Typename field of type String with `graphql:"__typename"` tag,
+// to find out the concrete type afterwards.
+func Typename(v any) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	return typename(rv)
+}
+
+// typename returns the value of the __typename field of struct v, if any.
+func typename(v reflect.Value) string {
+	fs, err := fields.Of(v.Type())
+	if err != nil {
+		return ""
+	}
+	for _, f := range fs {
+		fv := v.Field(f.Index)
+		switch {
+		case f.Name == "__typename" && fv.Kind() == reflect.String:
+			return fv.String()
+		case f.Inline && fv.Kind() == reflect.Struct:
+			if s := typename(fv); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// QueryT executes a single GraphQL query request with client c,
+// with a query derived from struct type T, and returns the response
+// populated into a new value of T. For example:
+//
+//	type viewerQuery struct {
+//		Viewer struct {
+//			Login graphql.String
+//		}
+//	}
+//
+//	q, err := graphql.QueryT[viewerQuery](ctx, client, nil)
+//
+// It returns an error without executing the query if T isn't a struct type.
+// If the response has errors, the partially populated value is returned
+// along with them.
+func QueryT[T any](ctx context.Context, c *Client, variables map[string]any, opts ...Option) (T, error) {
+	return doT[T](ctx, c, queryOperation, variables, opts)
+}
+
+// MutateT executes a single GraphQL mutation request with client c,
+// with a mutation derived from struct type T, and returns the response
+// populated into a new value of T. See QueryT for details.
+func MutateT[T any](ctx context.Context, c *Client, variables map[string]any, opts ...Option) (T, error) {
+	return doT[T](ctx, c, mutationOperation, variables, opts)
+}
+
+// doT executes a single GraphQL operation derived from struct type T.
+func doT[T any](ctx context.Context, c *Client, op operationType, variables map[string]any, opts []Option) (T, error) {
+	var v T
+	if t := reflect.TypeOf(&v).Elem(); t.Kind() != reflect.Struct {
+		return v, fmt.Errorf("cannot execute operation derived from %v, it must be a struct type", t)
+	}
+	err := c.do(ctx, op, &v, variables, c.options(opts))
+	return v, err
+}
@@ -15,52 +15,86 @@ import (
 type Client struct {
 	url        string       // GraphQL server URL.
 	httpClient *http.Client // Non-nil.
+	opts       []Option     // Options for all operations.
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
 // If httpClient is nil, then http.DefaultClient is used.
-func NewClient(url string, httpClient *http.Client) *Client {
+//
+// The options apply to all operations executed by the client. Options
+// provided to a call are applied after them, so they can override them.
+func NewClient(url string, httpClient *http.Client, opts ...Option) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 	return &Client{
 		url:        url,
 		httpClient: httpClient,
+		opts:       opts,
 	}
 }
 
+// options returns the configuration set by the options of c, followed by opts.
+func (c *Client) options(opts []Option) options {
+	return newOptions(append(c.opts[:len(c.opts):len(c.opts)], opts...))
+}
+
 // Query executes a single GraphQL query request,
 // with a query derived from q, populating the response into it.
 // q should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Query(ctx context.Context, q any, variables map[string]any) error {
-	return c.do(ctx, queryOperation, q, variables)
+func (c *Client) Query(ctx context.Context, q any, variables map[string]any, opts ...Option) error {
+	return c.do(ctx, queryOperation, q, variables, c.options(opts))
 }
 
 // Mutate executes a single GraphQL mutation request,
 // with a mutation derived from m, populating the response into it.
 // m should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Mutate(ctx context.Context, m any, variables map[string]any) error {
-	return c.do(ctx, mutationOperation, m, variables)
+func (c *Client) Mutate(ctx context.Context, m any, variables map[string]any, opts ...Option) error {
+	return c.do(ctx, mutationOperation, m, variables, c.options(opts))
+}
+
+// Exec executes a single GraphQL request with the provided query document,
+// which is sent as is, populating the response data into v. It's useful for
+// documents that can't be derived from a struct, e.g., ones with multiple
+// operations, where the OperationName option selects the one to execute.
+//
+// v can be a pointer to struct that corresponds to the selection sets of
+// the document, which is populated the same way as by Query, or a pointer
+// to map[string]any or json.RawMessage, which is populated by encoding/json,
+// or a pointer to Result.
+// If v is nil, the response data is discarded.
+//
+// Options that affect how documents are constructed have no effect.
+func (c *Client) Exec(ctx context.Context, query string, v any, variables map[string]any, opts ...Option) error {
+	return c.exec(ctx, query, v, variables, c.options(opts))
 }
 
 // do executes a single GraphQL operation.
-func (c *Client) do(ctx context.Context, op operationType, v any, variables map[string]any) error {
-	var query string
-	switch op {
-	case queryOperation:
-		query = constructQuery(v, variables)
-	case mutationOperation:
-		query = constructMutation(v, variables)
+func (c *Client) do(ctx context.Context, op operationType, v any, variables map[string]any, opts options) error {
+	o, err := construct(op, v, variables, opts)
+	if err != nil {
+		return err
+	}
+	return c.exec(ctx, o.query, v, o.variables(variables), opts)
+}
+
+// exec executes a single GraphQL request with query document query.
+func (c *Client) exec(ctx context.Context, query string, v any, variables map[string]any, opts options) error {
+	variables, err := opts.scalars.encodeVariables(variables)
+	if err != nil {
+		return err
 	}
 	in := struct {
-		Query     string         `json:"query"`
-		Variables map[string]any `json:"variables,omitempty"`
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName,omitempty"`
+		Variables     map[string]any `json:"variables,omitempty"`
 	}{
-		Query:     query,
-		Variables: variables,
+		Query:         query,
+		OperationName: opts.operationName,
+		Variables:     variables,
 	}
 	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(in)
+	err = json.NewEncoder(&buf).Encode(in)
 	if err != nil {
 		return err
 	}
@@ -78,29 +112,114 @@ func (c *Client) do(ctx context.Context, op operationType, v any, variables map[
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("non-200 OK status code: %v body: %q", resp.Status, body)
 	}
-	var out struct {
-		Data   *json.RawMessage
-		Errors errors
-		//Extensions any // Unused.
-	}
-	err = json.NewDecoder(resp.Body).Decode(&out)
+	var errs errors
+	err = decodeResponse(resp.Body, v, &errs, opts)
 	if err != nil {
 		// TODO: Consider including response body in returned error, if deemed helpful.
 		return err
 	}
-	if out.Data != nil {
-		err := jsonutil.UnmarshalGraphQL(*out.Data, v)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodeResponse decodes a GraphQL response from r. The response data is
+// decoded into v as it's read, without buffering the whole response first.
+// The response errors, which may come before or after the data, are decoded
+// into errs. If the data can't be decoded, but there are response errors,
+// which likely explain why, the data error isn't returned.
+func decodeResponse(r io.Reader, v any, errs *errors, opts options) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("invalid response, want a JSON object, got token '%v'", tok)
+	}
+	var dataErr error
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "data":
+			err = decodeData(dec, v, opts)
+			if _, ok := err.(*DecodeError); ok {
+				// The rest of the data is skipped. Keep reading the response,
+				// in case it has errors.
+				dataErr, err = err, nil
+			}
+		case "errors":
+			err = dec.Decode(errs)
+		default:
+			// Skip other fields, such as extensions.
+			err = skipValue(dec)
+		}
 		if err != nil {
-			// TODO: Consider including response body in returned error, if deemed helpful.
 			return err
 		}
 	}
-	if len(out.Errors) > 0 {
-		return out.Errors
+	_, err = dec.Token() // End of response object.
+	if err != nil {
+		return err
+	}
+	if len(*errs) == 0 {
+		return dataErr
 	}
 	return nil
 }
 
+// decodeData decodes the response data from dec into v.
+// See Client.Exec for what v can be.
+func decodeData(dec *json.Decoder, v any, opts options) error {
+	switch v := v.(type) {
+	case nil:
+		return skipValue(dec)
+	case *json.RawMessage, *Result:
+		return dec.Decode(v)
+	case *map[string]any:
+		// Decode numbers as float64 rather than json.Number.
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v)
+	default:
+		return jsonutil.DecodeGraphQL(dec, v, opts.decode)
+	}
+}
+
+// skipValue reads the next JSON value from dec and discards it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// DecodeError is the error returned when response data can't be decoded
+// into the Go value provided for it. It reports the response path of the
+// value, such as "repository.issues.nodes[3].author.login", the Go struct
+// field and type it was being decoded into, and the offending JSON token.
+type DecodeError = jsonutil.DecodeError
+
 // errors represents the "errors" array in a response from a GraphQL server.
 // If returned via error interface, the slice is expected to contain at least 1 element.
 //
@@ -2,10 +2,17 @@ package graphql_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/shurcooL/graphql"
 )
@@ -100,6 +107,135 @@ func TestClient_Query_noDataWithErrorResponse(t *testing.T) {
 	}
 }
 
+// Test that null response data, or data that can't be decoded,
+// doesn't hide the response errors.
+func TestClient_Query_errorsWithNullData(t *testing.T) {
+	responses := map[string]string{
+		"null":      `{"data": null, "errors": [{"message": "boom"}]}`,
+		"undecoded": `{"data": {"viewer": {"status": null, "login": "gopher"}}, "errors": [{"message": "boom", "path": ["viewer", "status"]}]}`,
+	}
+	for name, response := range responses {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, response)
+		})
+		var nulls graphql.Nulls
+		for _, opt := range []graphql.Option{graphql.DisallowNullStructs(), graphql.RecordNulls(&nulls)} {
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, opt)
+			var q struct {
+				Viewer struct {
+					Status struct {
+						Message graphql.String
+					}
+					Login graphql.String
+				}
+			}
+			err := client.Query(context.Background(), &q, nil)
+			if got, want := fmt.Sprint(err), "boom"; got != want {
+				t.Errorf("%s: got error: %v, want: %v", name, got, want)
+			}
+		}
+		if got, want := nulls.Has(""), false; got != want {
+			t.Errorf("%s: got nulls.Has(\"\"): %v, want: %v", name, got, want)
+		}
+	}
+}
+
+func TestClient_Query_errorsBeforeData(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"errors": [{"message": "Could not resolve to a node with the global id of 'NotExist'", "path": ["node2"]}],
+			"extensions": {"cost": {"requested": 2, "nested": [[{}]]}},
+			"data": {"node1": {"id": "MDEy"}, "node2": null}
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Node1 *struct {
+			ID graphql.ID
+		} `graphql:"node1: node(id: \"MDEy\")"`
+		Node2 *struct {
+			ID graphql.ID
+		} `graphql:"node2: node(id: \"NotExist\")"`
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), "Could not resolve to a node with the global id of 'NotExist'"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	if q.Node1 == nil || q.Node1.ID != "MDEy" {
+		t.Errorf("got wrong q.Node1: %v", q.Node1)
+	}
+	if q.Node2 != nil {
+		t.Errorf("got non-nil q.Node2: %v, want: nil", *q.Node2)
+	}
+}
+
+func TestClient_Query_invalidResponse(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: `[]`, want: "invalid response, want a JSON object, got token '['"},
+		{body: `{"data": {"viewer": {"login": "gopher"}`, want: "unexpected end of JSON input"},
+		{body: `{"data": {"viewer": {"name": "Gopher"}}}`, want: `decoding viewer.name at token "name": struct field for "name" doesn't exist in any of 1 places to unmarshal`},
+	}
+	for _, tc := range tests {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, tc.body)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+		var q struct {
+			Viewer struct {
+				Login graphql.String
+			}
+		}
+		err := client.Query(context.Background(), &q, nil)
+		if got := fmt.Sprint(err); got != tc.want {
+			t.Errorf("body %s:\n got error: %v\nwant error: %v", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestClient_Query_lenientDecoding(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher", "status": {"emoji": ":wave:"}}}}`)
+	})
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err := client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), `decoding viewer.status at token "status": struct field for "status" doesn't exist in any of 1 places to unmarshal`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	err = client.Query(context.Background(), &q, nil, graphql.LenientDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.LenientDecoding())
+	q.Viewer.Login = ""
+	err = client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+}
+
 func TestClient_Query_errorStatusCode(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
@@ -152,29 +288,975 @@ func TestClient_Query_emptyVariables(t *testing.T) {
 	}
 }
 
-// localRoundTripper is an http.RoundTripper that executes HTTP transactions
-// by using handler directly, instead of going over an HTTP connection.
-type localRoundTripper struct {
-	handler http.Handler
+// Test that a query that can't be constructed
+// is reported before making any request.
+func TestClient_Query_constructionError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		t.Error("unexpected request")
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name      graphql.String
+			Followers map[int]graphql.String
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), "struct field User.Followers: map type map[int]graphql.String can't be represented in a GraphQL query, only maps with string keys can"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
 }
 
-func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	w := httptest.NewRecorder()
-	l.handler.ServeHTTP(w, req)
-	return w.Result(), nil
+func TestClient_Query_fieldArguments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{human(id:\"1000\"){name,height(unit:METER)}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"human": {"name": "Luke Skywalker", "height": 1.72}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Human struct {
+			Name   graphql.String
+			Height graphql.Float
+		}
+	}
+	err := client.Query(context.Background(), &q, nil,
+		graphql.FieldArguments("human", map[string]any{"id": "1000"}),
+		graphql.FieldArguments("human.height", map[string]any{"unit": graphql.Enum("METER")}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Human.Name, graphql.String("Luke Skywalker"); got != want {
+		t.Errorf("got q.Human.Name: %q, want: %q", got, want)
+	}
 }
 
-func mustRead(r io.Reader) string {
-	b, err := io.ReadAll(r)
+func TestClient_Query_hoistArguments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($v1:ID!){human(id:$v1){name}}","variables":{"v1":"1000"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"human": {"name": "Luke Skywalker"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Human struct {
+			Name graphql.String
+		} `graphql:"human(id: \"1000\")"`
+	}
+	err := client.Query(context.Background(), &q, nil, graphql.HoistArguments(map[string]string{"human.id": "ID!"}))
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	if got, want := q.Human.Name, graphql.String("Luke Skywalker"); got != want {
+		t.Errorf("got q.Human.Name: %q, want: %q", got, want)
 	}
-	return string(b)
 }
 
-func mustWrite(w io.Writer, s string) {
-	_, err := io.WriteString(w, s)
+type characterFields struct {
+	Name graphql.String
+}
+
+func (characterFields) GraphQLFragment() string { return "CharacterFields on Character" }
+
+func TestClient_Query_namedFragments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{hero{...CharacterFields,friends{...CharacterFields}}}fragment CharacterFields on Character{name}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"hero": {"name": "R2-D2", "friends": [{"name": "Luke Skywalker"}, {"name": "Han Solo"}]}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Hero struct {
+			characterFields
+			Friends []characterFields
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	if got, want := q.Hero.Name, graphql.String("R2-D2"); got != want {
+		t.Errorf("got q.Hero.Name: %q, want: %q", got, want)
+	}
+	if got, want := len(q.Hero.Friends), 2; got != want {
+		t.Fatalf("got len(q.Hero.Friends): %v, want: %v", got, want)
+	}
+	if got, want := q.Hero.Friends[1].Name, graphql.String("Han Solo"); got != want {
+		t.Errorf("got q.Hero.Friends[1].Name: %q, want: %q", got, want)
+	}
+}
+
+type (
+	humanFields struct {
+		Name   graphql.String
+		Height graphql.Float
+	}
+	droidFields struct {
+		Name            graphql.String
+		PrimaryFunction graphql.String
+	}
+)
+
+func (humanFields) GraphQLFragment() string { return "HumanFields on Human" }
+func (droidFields) GraphQLFragment() string { return "DroidFields on Droid" }
+
+func TestClient_Query_namedFragmentTypes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{hero{__typename,...HumanFields,...DroidFields}}fragment HumanFields on Human{name,height}fragment DroidFields on Droid{name,primaryFunction}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"hero": {"__typename": "Droid", "name": "R2-D2", "primaryFunction": "Astromech"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Hero struct {
+			humanFields
+			droidFields
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Hero.droidFields.Name, graphql.String("R2-D2"); got != want {
+		t.Errorf("got q.Hero.droidFields.Name: %q, want: %q", got, want)
+	}
+	if got := q.Hero.humanFields.Name; got != "" {
+		t.Errorf("got q.Hero.humanFields.Name: %q, want empty", got)
+	}
+}
+
+func TestClient_Query_typename(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{hero{__typename,... on Droid{name,primaryFunction},... on Human{name,height}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"hero": {"__typename": "Droid", "name": "R2-D2", "primaryFunction": "Astromech"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Hero struct {
+			Droid struct {
+				Name            graphql.String
+				PrimaryFunction graphql.String
+			} `graphql:"... on Droid"`
+			Human struct {
+				Name   graphql.String
+				Height graphql.Float
+			} `graphql:"... on Human"`
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Hero.Droid.Name, graphql.String("R2-D2"); got != want {
+		t.Errorf("got q.Hero.Droid.Name: %q, want: %q", got, want)
+	}
+	if got, want := q.Hero.Human.Name, graphql.String(""); got != want {
+		t.Errorf("got q.Hero.Human.Name: %q, want: %q", got, want)
+	}
+}
+
+type (
+	searchResult interface{ isSearchResult() }
+	issue        struct {
+		Title graphql.String
+	}
+	repository struct {
+		NameWithOwner graphql.String
+	}
+)
+
+func (issue) isSearchResult()       {}
+func (*repository) isSearchResult() {}
+
+func init() {
+	graphql.RegisterPossibleTypes(map[string]searchResult{
+		"Issue":      issue{},
+		"Repository": &repository{},
+	})
+}
+
+func TestClient_Query_possibleTypes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{search(query:\"graphql\",type:ISSUE,first:2){__typename,... on Issue{title},... on Repository{nameWithOwner}}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"search": [{"__typename": "Repository", "nameWithOwner": "shurcooL/graphql"}, {"__typename": "Issue", "title": "Unions"}]}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Search []searchResult `graphql:"search(query: \"graphql\", type: ISSUE, first: 2)"`
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(q.Search), 2; got != want {
+		t.Fatalf("got len(q.Search): %v, want: %v", got, want)
+	}
+	if got, ok := q.Search[0].(*repository); !ok || got.NameWithOwner != "shurcooL/graphql" {
+		t.Errorf("got q.Search[0]: %#v, want: *repository with NameWithOwner %q", q.Search[0], "shurcooL/graphql")
+	}
+	if got, ok := q.Search[1].(issue); !ok || got.Title != "Unions" {
+		t.Errorf("got q.Search[1]: %#v, want: issue with Title %q", q.Search[1], "Unions")
+	}
+}
+
+func TestClient_Exec(t *testing.T) {
+	const query = `query Hero { hero { name } }
+query Droid($id: ID!) { droid(id: $id) { name primaryFunction } }`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query Hero { hero { name } }\nquery Droid($id: ID!) { droid(id: $id) { name primaryFunction } }","operationName":"Droid","variables":{"id":"2001"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"droid": {"name": "R2-D2", "primaryFunction": "Astromech"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	variables := map[string]any{"id": graphql.ID("2001")}
+
+	var q struct {
+		Droid struct {
+			Name            graphql.String
+			PrimaryFunction graphql.String
+		}
+	}
+	err := client.Exec(context.Background(), query, &q, variables, graphql.OperationName("Droid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Droid.PrimaryFunction, graphql.String("Astromech"); got != want {
+		t.Errorf("got q.Droid.PrimaryFunction: %q, want: %q", got, want)
+	}
+
+	var m map[string]any
+	err = client.Exec(context.Background(), query, &m, variables, graphql.OperationName("Droid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m, map[string]any{"droid": map[string]any{"name": "R2-D2", "primaryFunction": "Astromech"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got m: %v, want: %v", got, want)
+	}
+
+	var raw json.RawMessage
+	err = client.Exec(context.Background(), query, &raw, variables, graphql.OperationName("Droid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(raw), `{"droid": {"name": "R2-D2", "primaryFunction": "Astromech"}}`; got != want {
+		t.Errorf("got raw: %s, want: %s", got, want)
+	}
+
+	err = client.Exec(context.Background(), query, nil, variables, graphql.OperationName("Droid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_operationName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query Viewer{viewer{login}}","operationName":"Viewer"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	err := client.Query(context.Background(), &q, nil, graphql.OperationName("Viewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_QuerySelections(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($first:Int!){viewer{login,repositories(first:$first){nodes{name,stargazerCount}}}}","variables":{"first":2}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher", "repositories": {"nodes": [{"name": "a", "stargazerCount": 10}, {"name": "b", "stargazerCount": 1.5}]}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	q := graphql.Selections{
+		graphql.Select("viewer",
+			graphql.Select("login"),
+			graphql.Select("repositories",
+				graphql.Select("nodes",
+					graphql.Select("name"),
+					graphql.Select("stargazerCount"),
+				),
+			).Args(map[string]any{"first": graphql.Variable("first")}),
+		),
+	}
+	r, err := client.QuerySelections(context.Background(), q, map[string]any{"first": graphql.Int(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer := r.Get("viewer")
+	if got, ok := viewer.Get("login").AsString(); !ok || got != "gopher" {
+		t.Errorf("got login: %q, %v, want: %q, true", got, ok, "gopher")
+	}
+	nodes := viewer.Get("repositories").Get("nodes")
+	if got, want := nodes.Len(), 2; got != want {
+		t.Fatalf("got nodes.Len(): %v, want: %v", got, want)
+	}
+	if got, ok := nodes.Index(0).Get("stargazerCount").AsInt(); !ok || got != 10 {
+		t.Errorf("got stargazerCount: %v, %v, want: 10, true", got, ok)
+	}
+	if _, ok := nodes.Index(1).Get("stargazerCount").AsInt(); ok {
+		t.Error("got AsInt of 1.5 ok: true, want: false")
+	}
+	if got, ok := nodes.Index(1).Get("stargazerCount").AsFloat(); !ok || got != 1.5 {
+		t.Errorf("got stargazerCount: %v, %v, want: 1.5, true", got, ok)
+	}
+	if got, want := viewer.Keys(), []string{"login", "repositories"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got viewer.Keys(): %v, want: %v", got, want)
+	}
+	if missing := nodes.Index(2).Get("name"); missing.Exists() || missing.IsNull() {
+		t.Errorf("got missing.Exists(), missing.IsNull(): %v, %v, want: false, false", missing.Exists(), missing.IsNull())
+	}
+	if _, ok := r.Value().(map[string]any); !ok {
+		t.Errorf("got r.Value() of type %T, want map[string]any", r.Value())
+	}
+}
+
+func TestQueryT(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query Viewer($avatarSize:Int!){viewer{login,avatarUrl(size:$avatarSize)}}","operationName":"Viewer","variables":{"avatarSize":72}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher", "avatarUrl": "https://example.org/gopher.png"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type viewerQuery struct {
+		Viewer struct {
+			Login     graphql.String
+			AvatarURL graphql.String `graphql:"avatarUrl(size:$avatarSize)"`
+		}
+	}
+	q, err := graphql.QueryT[viewerQuery](context.Background(), client, map[string]any{
+		"avatarSize": graphql.Int(72),
+	}, graphql.OperationName("Viewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+	}
+
+	_, err = graphql.MutateT[*viewerQuery](context.Background(), client, nil)
+	if got, want := fmt.Sprint(err), "cannot execute operation derived from *graphql_test.viewerQuery, it must be a struct type"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestPages(t *testing.T) {
+	responses := map[string]string{
+		`null`:  `{"data": {"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}], "pageInfo": {"hasNextPage": true, "hasPreviousPage": false, "startCursor": "c1", "endCursor": "c2"}}}}}`,
+		`"c2"`:  `{"data": {"repository": {"issues": {"nodes": [{"number": 3}, {"number": 4}], "pageInfo": {"hasNextPage": true, "hasPreviousPage": true, "startCursor": "c3", "endCursor": "c4"}}}}}`,
+		`"c4"`:  `{"data": {"repository": {"issues": {"nodes": [{"number": 5}], "pageInfo": {"hasNextPage": false, "hasPreviousPage": true, "startCursor": "c3", "endCursor": "c5"}}}}}`,
+		`"c3"`:  `{"data": {"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}], "pageInfo": {"hasNextPage": true, "hasPreviousPage": false, "startCursor": "c1", "endCursor": "c2"}}}}}`,
+		`"bad"`: `{"data": {"repository": {"issues": {"nodes": [], "pageInfo": {"hasNextPage": true, "hasPreviousPage": false, "startCursor": null, "endCursor": null}}}}}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]json.RawMessage
+		}
+		err := json.NewDecoder(req.Body).Decode(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := in.Query, `query($cursor:String$first:Int!){repository{issues(first:$first,after:$cursor){nodes{number},pageInfo{hasNextPage,hasPreviousPage,startCursor,endCursor}}}}`; got != want {
+			t.Errorf("got query: %v, want: %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, responses[string(in.Variables["cursor"])])
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type issue struct {
+		Number graphql.Int
+	}
+	type issuesQuery struct {
+		Repository struct {
+			Issues struct {
+				Nodes    []issue
+				PageInfo graphql.PageInfo
+			} `graphql:"issues(first:$first,after:$cursor)"`
+		}
+	}
+	issues := func(q *issuesQuery) ([]issue, graphql.PageInfo) {
+		return q.Repository.Issues.Nodes, q.Repository.Issues.PageInfo
+	}
+	numbers := func(variables map[string]any, opts ...graphql.Option) ([]graphql.Int, error) {
+		var numbers []graphql.Int
+		err := graphql.Paginate(context.Background(), client, variables, "cursor", issues, func(i issue) error {
+			numbers = append(numbers, i.Number)
+			return nil
+		}, opts...)
+		return numbers, err
+	}
+
+	variables := map[string]any{"first": graphql.Int(2)}
+	got, err := numbers(variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []graphql.Int{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got numbers: %v, want: %v", got, want)
+	}
+	if len(variables) != 1 {
+		t.Errorf("variables were modified: %v", variables)
+	}
+
+	got, err = numbers(variables, graphql.MaxPages(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []graphql.Int{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got numbers with MaxPages(2): %v, want: %v", got, want)
+	}
+
+	c4 := graphql.String("c4")
+	got, err = numbers(map[string]any{"first": graphql.Int(2), "cursor": &c4}, graphql.Backward())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []graphql.Int{5, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got numbers with Backward: %v, want: %v", got, want)
+	}
+
+	bad := graphql.String("bad")
+	_, err = numbers(map[string]any{"first": graphql.Int(2), "cursor": &bad})
+	if got, want := fmt.Sprint(err), "page 1 of connection has more pages, but no cursor"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+
+	// Stop on the first error returned by fn.
+	errStop := errors.New("stop")
+	var seen int
+	err = graphql.Paginate(context.Background(), client, variables, "cursor", issues, func(i issue) error {
+		seen++
+		if i.Number == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || seen != 3 {
+		t.Errorf("got error %v after %d nodes, want: %v after 3", err, seen, errStop)
+	}
+
+	// Pages are iterated over with their nodes.
+	var sizes []int
+	pages := graphql.NewPages(client, variables, "cursor", issues)
+	for pages.Next(context.Background()) {
+		if got, want := pages.Nodes(), pages.Page().Repository.Issues.Nodes; !reflect.DeepEqual(got, want) {
+			t.Errorf("got pages.Nodes: %v, want: %v", got, want)
+		}
+		sizes = append(sizes, len(pages.Nodes()))
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 2, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("got page sizes: %v, want: %v", sizes, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pages = graphql.NewPages(client, variables, "cursor", issues)
+	if !pages.Next(ctx) {
+		t.Fatalf("got first pages.Next: false, want: true; error: %v", pages.Err())
+	}
+	cancel()
+	if pages.Next(ctx) {
+		t.Error("got pages.Next after cancel: true, want: false")
+	}
+	if got, want := pages.Err(), context.Canceled; got != want {
+		t.Errorf("got pages.Err(): %v, want: %v", got, want)
+	}
+}
+
+// localRoundTripper is an http.RoundTripper that executes HTTP transactions
+// by using handler directly, instead of going over an HTTP connection.
+type localRoundTripper struct {
+	handler http.Handler
+}
+
+func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	l.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+func mustRead(r io.Reader) string {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func mustWrite(w io.Writer, s string) {
+	_, err := io.WriteString(w, s)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type userFields struct {
+		Login graphql.String
+	}
+	type query struct {
+		Viewer struct {
+			userFields
+			Name      graphql.String
+			AvatarURL graphql.String `graphql:"smallAvatar: avatarUrl(size: 32)"`
+			CreatedAt time.Time
+		}
+		Node *struct {
+			ID   graphql.ID
+			User struct {
+				Bio graphql.String
+			} `graphql:"... on User"`
+		}
+		Tags    []graphql.String
+		Missing *struct {
+			ID graphql.ID
+		}
+	}
+	const data = `{"viewer":{"login":"gopher","name":"Gopher","smallAvatar":"https://example.org/a.png","createdAt":"2017-06-29T04:12:01Z"},"node":{"id":"MDQ6","bio":"Hi."},"tags":["a","b"],"missing":null}`
+
+	var q query
+	err := graphql.Unmarshal([]byte(data), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Viewer.Login = "gopher"
+	want.Viewer.Name = "Gopher"
+	want.Viewer.AvatarURL = "https://example.org/a.png"
+	want.Viewer.CreatedAt = time.Unix(1498709521, 0).UTC()
+	want.Node = &struct {
+		ID   graphql.ID
+		User struct {
+			Bio graphql.String
+		} `graphql:"... on User"`
+	}{ID: "MDQ6"}
+	want.Node.User.Bio = "Hi."
+	want.Tags = []graphql.String{"a", "b"}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("not equal:\n got: %+v\nwant: %+v", q, want)
+	}
+
+	b, err := graphql.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != data {
+		t.Errorf("got Marshal:\n%s\nwant:\n%s", got, data)
+	}
+
+	err = graphql.Unmarshal([]byte(`{"viewer":{"login":"gopher","bio":""}}`), &q)
+	if got, want := fmt.Sprint(err), `decoding viewer.bio at token "bio": struct field for "bio" doesn't exist in any of 2 places to unmarshal`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	err = graphql.Unmarshal([]byte(`{"viewer":{"login":"gopher","bio":""}}`), &q, graphql.LenientDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = graphql.Unmarshal([]byte(`{"tags":[]} {}`), &q)
+	if got, want := fmt.Sprint(err), "invalid token '{' after top-level value"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			characterFields
+			Login graphql.String `graphql:"handle: login"`
+		}
+		Hero struct {
+			Typename graphql.String `graphql:"__typename"`
+			Name     graphql.String
+			Human    struct {
+				Name   graphql.String
+				Height graphql.Float
+			} `graphql:"... on Human"`
+			Droid struct {
+				Name            graphql.String
+				PrimaryFunction graphql.String
+			} `graphql:"... on Droid"`
+		} `graphql:"hero(episode: EMPIRE)"`
+		Search  []searchResult `graphql:"search(query: \"graphql\", type: ISSUE, first: 2)"`
+		Friends [2]*struct {
+			Name graphql.String
+		}
+	}
+	const data = `{"viewer":{"name":"Luke Skywalker","handle":"luke"},"hero":{"__typename":"Droid","name":"R2-D2","primaryFunction":"Astromech"},"search":[{"__typename":"Repository","nameWithOwner":"shurcooL/graphql"},{"__typename":"Issue","title":"Unions"}],"friends":[{"name":"Han Solo"},null]}`
+
+	var q query
+	err := graphql.Unmarshal([]byte(data), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Hero.Droid.Name, graphql.String("R2-D2"); got != want {
+		t.Errorf("got q.Hero.Droid.Name: %q, want: %q", got, want)
+	}
+	b, err := graphql.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != data {
+		t.Errorf("got Marshal:\n%s\nwant:\n%s", got, data)
+	}
+
+	var q2 query
+	err = graphql.Unmarshal(b, &q2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("round trip not equal:\n got: %+v\nwant: %+v", q2, q)
+	}
+}
+
+type (
+	actor interface{ isActor() }
+	user  struct {
+		Login  graphql.String
+		Status struct {
+			Message graphql.String
+		}
+	}
+)
+
+func (user) isActor() {}
+
+func init() {
+	graphql.RegisterPossibleTypes(map[string]actor{"User": user{}})
+}
+
+func TestUnmarshal_nullStructs(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Status struct {
+				Message graphql.String
+			}
+		}
+		Search []actor
+	}
+	const data = `{"viewer":{"status":{}},"search":[{"__typename":"User","login":"gopher","status":null}]}`
+	var nulls graphql.Nulls
+	err := graphql.Unmarshal([]byte(data), &q, graphql.RecordNulls(&nulls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := nulls.Paths(), []string{"search[0].status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got nulls.Paths: %q, want: %q", got, want)
+	}
+	if nulls.Has("viewer.status") {
+		t.Error("got nulls.Has(viewer.status): true, want: false")
+	}
+
+	err = graphql.Unmarshal([]byte(data), &q, graphql.DisallowNullStructs())
+	var e *graphql.DecodeError
+	if !errors.As(err, &e) {
+		t.Fatalf("got error: %v, want a DecodeError", err)
+	}
+	if got, want := e.Path, "search[0].status"; got != want {
+		t.Errorf("got DecodeError.Path: %q, want: %q", got, want)
+	}
+}
+
+// decimal is a string-backed decimal number, a custom scalar without JSON methods.
+type decimal string
+
+func TestClient_Query_customScalars(t *testing.T) {
+	scalars := []graphql.Option{
+		graphql.TimeScalar("Date", "2006-01-02"),
+		graphql.CustomScalar("BigInt",
+			func(n *big.Int) (any, error) { return json.Number(n.String()), nil },
+			func(value any) (*big.Int, error) {
+				n, ok := new(big.Int).SetString(fmt.Sprint(value), 10)
+				if !ok {
+					return nil, fmt.Errorf("invalid BigInt value %v", value)
+				}
+				return n, nil
+			},
+		),
+		graphql.CustomScalar("Decimal",
+			func(d decimal) (any, error) { return json.Number(d), nil },
+			func(value any) (decimal, error) { return decimal(fmt.Sprint(value)), nil },
+		),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($min:BigInt$since:Date!){repository(since:$since,min:$min){createdAt,diskUsage,price(above:12.5),pushedAt}}","variables":{"min":1180591620717411303424,"since":"2024-01-02"}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"repository": {"createdAt": "2020-05-06", "diskUsage": 1180591620717411303425, "price": 12.50, "pushedAt": null}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, scalars...)
+
+	type query struct {
+		Repository struct {
+			CreatedAt time.Time
+			DiskUsage *big.Int
+			Price     decimal
+			PushedAt  *time.Time
+		} `graphql:"repository(since: $since, min: $min)"`
+	}
+	variables := map[string]any{
+		"since": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"min":   new(big.Int).Lsh(big.NewInt(1), 70),
+	}
+	q, err := graphql.QueryT[query](context.Background(), client, variables, graphql.FieldArguments("repository.price", map[string]any{"above": decimal("12.5")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Repository.CreatedAt, time.Date(2020, 5, 6, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got q.Repository.CreatedAt: %v, want: %v", got, want)
+	}
+	if got, want := q.Repository.DiskUsage.String(), "1180591620717411303425"; got != want {
+		t.Errorf("got q.Repository.DiskUsage: %v, want: %v", got, want)
+	}
+	if got, want := q.Repository.Price, decimal("12.50"); got != want {
+		t.Errorf("got q.Repository.Price: %q, want: %q", got, want)
+	}
+	if q.Repository.PushedAt != nil {
+		t.Errorf("got q.Repository.PushedAt: %v, want: nil", q.Repository.PushedAt)
+	}
+
+	var q2 struct{ CreatedAt time.Time }
+	err = graphql.Unmarshal([]byte(`{"createdAt": 20200506}`), &q2, scalars...)
+	if got, want := fmt.Sprint(err), "decoding createdAt into Go struct field CreatedAt of type time.Time at token 20200506: Date value must be a string, not json.Number"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+// labels is a custom collection that can be both decoded and encoded.
+type labels struct {
+	names []*graphql.String
+}
+
+func (l *labels) AppendGraphQL() any {
+	l.names = append(l.names, new(graphql.String))
+	return l.names[len(l.names)-1]
+}
+
+func (l *labels) RangeGraphQL(fn func(elem any) bool) {
+	for _, name := range l.names {
+		if !fn(name) {
+			return
+		}
+	}
+}
+
+// appendOnly is a custom collection that can only be decoded.
+type appendOnly struct {
+	names []graphql.String
+}
+
+func (l *appendOnly) AppendGraphQL() any {
+	l.names = append(l.names, "")
+	return &l.names[len(l.names)-1]
+}
+
+func TestMarshal_fragmentsWithoutTypename(t *testing.T) {
+	var q struct {
+		Hero struct {
+			Human struct {
+				Name   graphql.String
+				Height graphql.Float
+			} `graphql:"... on Human"`
+			Droid struct {
+				Name            graphql.String
+				PrimaryFunction graphql.String
+			} `graphql:"... on Droid"`
+		}
+	}
+	err := graphql.Unmarshal([]byte(`{"hero":{"__typename":"Droid","name":"R2-D2","primaryFunction":"Astromech"}}`), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = graphql.Marshal(q)
+	if got, want := fmt.Sprint(err), "fragments on Human, Droid can't be told apart without __typename, add a struct field with `graphql:\"__typename\"` tag to their selection set"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+
+	var q2 struct {
+		Hero struct {
+			humanFields
+			droidFields
+		}
+	}
+	_, err = graphql.Marshal(q2)
+	if got, want := fmt.Sprint(err), "fragments on Human, Droid can't be told apart without __typename, add a struct field with `graphql:\"__typename\"` tag to their selection set"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestMarshal_customScalars(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login     graphql.String
+			CreatedAt time.Time
+			UpdatedAt *time.Time
+			ClosedAt  *time.Time
+			Holidays  []time.Time
+			Balances  []*big.Int
+		}
+	}
+	const data = `{"viewer":{"login":"gopher","createdAt":"2024-03-05","updatedAt":"2024-03-06","closedAt":null,"holidays":["2020-01-03","2020-12-25"],"balances":["1180591620717411303424",null]}}`
+	scalars := []graphql.Option{
+		graphql.TimeScalar("Date", "2006-01-02"),
+		graphql.CustomScalar("BigInt",
+			func(n *big.Int) (any, error) { return n.String(), nil },
+			func(value any) (*big.Int, error) {
+				n, ok := new(big.Int).SetString(fmt.Sprint(value), 10)
+				if !ok {
+					return nil, fmt.Errorf("invalid BigInt value %v", value)
+				}
+				return n, nil
+			},
+		),
+	}
+	err := graphql.Unmarshal([]byte(data), &q, scalars...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := graphql.Marshal(q, scalars...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != data {
+		t.Errorf("got Marshal:\n%s\nwant:\n%s", got, data)
+	}
+}
+
+func TestMarshal_collections(t *testing.T) {
+	var q struct {
+		Issue struct {
+			Labels labels `graphql:"labels: labelNames"`
+		}
+	}
+	const data = `{"issue":{"labels":["bug","help wanted"]}}`
+	err := graphql.Unmarshal([]byte(data), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := graphql.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != data {
+		t.Errorf("got Marshal:\n%s\nwant:\n%s", got, data)
+	}
+
+	var q2 struct {
+		Labels appendOnly
+	}
+	_, err = graphql.Marshal(q2)
+	if got, want := fmt.Sprint(err), "collection type graphql_test.appendOnly implements Appender, but not Ranger, so it can't be encoded as a list"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+// Test that only values of custom scalars are encoded by them in variables,
+// and other values, such as encoding.TextMarshaler ones, by encoding/json.
+func TestClient_Query_customScalarVariables(t *testing.T) {
+	type input struct {
+		Addr  net.IP     `json:"addr"`
+		Raw   []byte     `json:"raw"`
+		Since time.Time  `json:"since"`
+		Until *time.Time `json:"until,omitempty"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"query($filter:input!){viewer{login}}","variables":{"filter":{"addr":"1.2.3.4","raw":"aGk=","since":"2024-03-05"}}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.TimeScalar("Date", "2006-01-02"))
+
+	var q struct {
+		Viewer struct {
+			Login graphql.String
+		}
+	}
+	variables := map[string]any{
+		"filter": input{
+			Addr:  net.ParseIP("1.2.3.4").To4(),
+			Raw:   []byte("hi"),
+			Since: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	err := client.Query(context.Background(), &q, variables)
+	if err != nil {
+		t.Fatal(err)
 	}
 }
@@ -0,0 +1,136 @@
+// Package fields provides cached GraphQL metadata about the fields
+// of struct types that are used as GraphQL query data structures.
+package fields
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/shurcooL/graphql/ident"
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// Field describes a single struct field of a GraphQL query data structure.
+type Field struct {
+	Index    int    // Index of the field in its struct, for use with reflect.Value.Field.
+	Exported bool   // Whether the field is exported.
+	Name     string // GraphQL name (response key) of the field, or empty if it doesn't have one.
+	Tagged   bool   // Whether the field has a graphql struct tag.
+
+	// Selection is the parsed graphql struct tag, a *language.Field
+	// or *language.InlineFragment. It's nil if the field has no tag,
+	// and is written by language.WriteSelection otherwise.
+	// A *language.Field may have a selection set, e.g., for a field
+	// of map type, that's written instead of one derived from its type.
+	Selection language.Selection
+
+	// Fragment reports whether the field is a GraphQL inline fragment,
+	// e.g., a field with `graphql:"... on User"` struct tag.
+	Fragment bool
+
+	// Conditional reports whether the field or inline fragment has
+	// an @include or @skip directive, so it may be absent from the response.
+	Conditional bool
+
+	// TypeCondition is the type condition of an inline fragment or of
+	// an embedded named fragment, e.g., "User". It's empty for fields,
+	// other embedded structs and inline fragments without a type condition.
+	TypeCondition string
+
+	// Inline reports whether the field is an embedded struct without
+	// a graphql struct tag, whose fields are inlined into parent struct.
+	Inline bool
+}
+
+// HasName reports whether f has GraphQL name.
+func (f Field) HasName(name string) bool {
+	if !f.Tagged {
+		// The name of an untagged field is derived from its Go name, e.g.,
+		// "databaseId" for DatabaseID, but the schema may case initialisms
+		// differently, e.g., "databaseID". Match it case-insensitively.
+		return strings.EqualFold(f.Name, name)
+	}
+	return f.Name == name
+}
+
+// cache maps a struct type to its *structFields.
+var cache sync.Map
+
+type structFields struct {
+	fields []Field
+	err    error
+}
+
+// Of returns the fields of struct type t, in order.
+// It returns an error if a graphql struct tag of t is invalid.
+// It's safe for concurrent use. The returned slice must not be modified.
+func Of(t reflect.Type) ([]Field, error) {
+	if sf, ok := cache.Load(t); ok {
+		return sf.(*structFields).fields, sf.(*structFields).err
+	}
+	fs, err := typeFields(t)
+	sf, _ := cache.LoadOrStore(t, &structFields{fields: fs, err: err})
+	return sf.(*structFields).fields, sf.(*structFields).err
+}
+
+// typeFields computes the fields of struct type t.
+func typeFields(t reflect.Type) ([]Field, error) {
+	fs := make([]Field, t.NumField())
+	for i := range fs {
+		sf := t.Field(i)
+		value, ok := sf.Tag.Lookup("graphql")
+		f := Field{
+			Index:    i,
+			Exported: sf.PkgPath == "",
+			Tagged:   ok,
+			Inline:   sf.Anonymous && !ok,
+		}
+		switch {
+		case f.Inline:
+			// Fields of embedded struct are inlined into parent struct.
+			// An embedded named fragment is spread into it, and applies
+			// only to its type condition, like an inline fragment.
+			if sf.Type.Kind() == reflect.Struct && IsNamedFragment(sf.Type) {
+				// An invalid named fragment is reported when writing the query.
+				_, f.TypeCondition, _ = NamedFragment(sf.Type)
+			}
+		case !ok:
+			f.Name = ident.ParseMixedCaps(sf.Name).ToLowerCamelCase()
+		default:
+			sel, err := language.ParseTag(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid graphql struct tag %q on %s: %w", value, fieldName(t, sf), err)
+			}
+			f.Selection = sel
+			var directives []*language.Directive
+			switch sel := sel.(type) {
+			case *language.Field:
+				f.Name = sel.ResponseKey()
+				directives = sel.Directives
+			case *language.InlineFragment:
+				f.Fragment = true
+				f.TypeCondition = sel.TypeCondition
+				directives = sel.Directives
+			}
+			for _, d := range directives {
+				if d.Name == "include" || d.Name == "skip" {
+					f.Conditional = true
+				}
+			}
+		}
+		fs[i] = f
+	}
+	return fs, nil
+}
+
+// fieldName returns a description of struct field sf of struct type t
+// for use in error messages, e.g., "struct field Query.Viewer".
+func fieldName(t reflect.Type, sf reflect.StructField) string {
+	if t.Name() == "" {
+		// Unnamed struct types are too verbose to include.
+		return "struct field " + sf.Name
+	}
+	return "struct field " + t.String() + "." + sf.Name
+}
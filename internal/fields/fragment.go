@@ -0,0 +1,39 @@
+package fields
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// namedFragment is the method set of graphql.NamedFragment.
+var namedFragment = reflect.TypeOf((*interface{ GraphQLFragment() string })(nil)).Elem()
+
+// IsNamedFragment reports whether struct type t is a named fragment,
+// i.e., it implements graphql.NamedFragment without promoting the method
+// from an embedded field.
+func IsNamedFragment(t reflect.Type) bool {
+	if !reflect.PtrTo(t).Implements(namedFragment) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && (sf.Type.Implements(namedFragment) || reflect.PtrTo(sf.Type).Implements(namedFragment)) {
+			return false
+		}
+	}
+	return true
+}
+
+// NamedFragment returns the name and type condition of named fragment t,
+// parsed from what its GraphQLFragment method returns, e.g., "UserFields on User".
+func NamedFragment(t reflect.Type) (name, typeCondition string, err error) {
+	s := reflect.New(t).Interface().(interface{ GraphQLFragment() string }).GraphQLFragment()
+	parts := strings.Fields(s)
+	if len(parts) != 3 || parts[1] != "on" || parts[0] == "on" || !language.IsName(parts[0]) || !language.IsName(parts[2]) {
+		return "", "", fmt.Errorf("invalid named fragment %q returned by %v.GraphQLFragment, want \"Name on Type\"", s, t)
+	}
+	return parts[0], parts[2], nil
+}
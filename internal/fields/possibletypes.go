@@ -0,0 +1,33 @@
+package fields
+
+import (
+	"reflect"
+	"sync"
+)
+
+// PossibleType is a Go type that corresponds to a possible type of
+// a GraphQL union or interface.
+type PossibleType struct {
+	Name string       // GraphQL type name, e.g., "Issue".
+	Type reflect.Type // Struct type or pointer to struct type.
+}
+
+// possibleTypes maps an interface type to its []PossibleType.
+var possibleTypes sync.Map
+
+// RegisterPossibleTypes registers types as the possible types of interface type t.
+// It reports whether t didn't have possible types registered already.
+func RegisterPossibleTypes(t reflect.Type, types []PossibleType) bool {
+	_, loaded := possibleTypes.LoadOrStore(t, types)
+	return !loaded
+}
+
+// PossibleTypes returns the possible types of interface type t, sorted by name,
+// or nil if none are registered. The returned slice must not be modified.
+func PossibleTypes(t reflect.Type) []PossibleType {
+	types, ok := possibleTypes.Load(t)
+	if !ok {
+		return nil
+	}
+	return types.([]PossibleType)
+}
@@ -0,0 +1,84 @@
+package fields
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Appender is the method set of graphql.Appender, implemented by pointers
+// to custom collection types that GraphQL lists are decoded into.
+type Appender interface {
+	AppendGraphQL() any
+}
+
+var appenderType = reflect.TypeOf((*Appender)(nil)).Elem()
+
+// IsAppender reports whether t is a custom collection type,
+// i.e., a non-pointer type whose pointer type implements Appender.
+func IsAppender(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(appenderType)
+}
+
+// AppenderElem returns the element type of the custom collection type t,
+// for which IsAppender reports true.
+func AppenderElem(t reflect.Type) (reflect.Type, error) {
+	elem := reflect.New(t).Interface().(Appender).AppendGraphQL()
+	p := reflect.ValueOf(elem)
+	if p.Kind() != reflect.Ptr || p.IsNil() {
+		return nil, fmt.Errorf("%v.AppendGraphQL must return a non-nil pointer to an element, not %T", t, elem)
+	}
+	return p.Type().Elem(), nil
+}
+
+// CapturesValue reports whether a value of type t is decoded from an entire
+// JSON value by encoding/json, rather than field by field, and is encoded
+// back by it. That's the case for maps with string keys, the empty interface
+// and json.Unmarshaler implementations, such as json.RawMessage, and pointers
+// to them. Lists of them aren't, their elements are decoded one by one.
+func CapturesValue(t reflect.Type) bool {
+	t = Indirect(t)
+	switch {
+	case reflect.PtrTo(t).Implements(jsonUnmarshaler):
+		return true
+	case t.Kind() == reflect.Map:
+		return t.Key().Kind() == reflect.String
+	case t.Kind() == reflect.Interface:
+		return t.NumMethod() == 0
+	default:
+		return false
+	}
+}
+
+// Indirect returns the type that t points to, following all pointers.
+func Indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// ListElem returns the type that the elements of lists of Go type t are
+// decoded into, following pointers, slices, arrays and custom collections.
+// Types that CapturesValue reports true for, such as json.RawMessage,
+// aren't followed, since they're decoded from entire JSON values.
+func ListElem(t reflect.Type) (reflect.Type, error) {
+	for {
+		switch {
+		case CapturesValue(t):
+			return t, nil
+		case IsAppender(t):
+			var err error
+			t, err = AppenderElem(t)
+			if err != nil {
+				return nil, err
+			}
+		case t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			t = t.Elem()
+		default:
+			return t, nil
+		}
+	}
+}
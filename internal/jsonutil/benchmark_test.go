@@ -125,3 +125,60 @@ func BenchmarkJSONTokenize(b *testing.B) {
 		}
 	}
 }
+
+// scalarsResponse is a response with many scalars of common kinds.
+var scalarsResponse = func() []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"repository": {"issues": {"totalCount": 100, "nodes": [`)
+	for i := 0; i < 100; i++ {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"id": "MDU6SXNzdWUx", "number": 1347, "title": "Found a bug", "closed": false, "score": 0.75, "milestone": null, "author": {"login": "octocat"}}`)
+	}
+	sb.WriteString(`]}}}`)
+	return []byte(sb.String())
+}()
+
+type scalarsQuery struct {
+	Repository struct {
+		Issues struct {
+			TotalCount graphql.Int
+			Nodes      []struct {
+				ID        graphql.ID
+				Number    int
+				Title     string
+				Closed    graphql.Boolean
+				Score     *float64
+				Milestone *graphql.String
+				Author    struct {
+					Login graphql.String
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkUnmarshalGraphQL_scalars(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(scalarsResponse)))
+	for i := 0; i < b.N; i++ {
+		var got scalarsQuery
+		err := jsonutil.UnmarshalGraphQL(scalarsResponse, &got)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONUnmarshal_scalars(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(scalarsResponse)))
+	for i := 0; i < b.N; i++ {
+		var got scalarsQuery
+		err := json.Unmarshal(scalarsResponse, &got)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
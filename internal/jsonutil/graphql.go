@@ -4,12 +4,16 @@ package jsonutil
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 	"strings"
+
+	"github.com/shurcooL/graphql/internal/fields"
 )
 
 // UnmarshalGraphQL parses the JSON-encoded GraphQL response data and stores
@@ -18,9 +22,36 @@ import (
 // The implementation is created on top of the JSON tokenizer available
 // in "encoding/json".Decoder.
 func UnmarshalGraphQL(data []byte, v any) error {
+	return UnmarshalGraphQLOptions(data, v, Options{})
+}
+
+// Options configures how GraphQL response data is decoded.
+type Options struct {
+	// Lenient makes object keys without a corresponding struct field
+	// be skipped, along with their values, rather than be an error.
+	Lenient bool
+
+	// DisallowNullStructs makes null be an error when it's decoded into
+	// a struct that isn't behind a pointer, rather than zeroing the struct.
+	DisallowNullStructs bool
+
+	// RecordNull, if not nil, is called with the response path of each
+	// null that's decoded into a struct that isn't behind a pointer.
+	RecordNull func(path string)
+
+	// Scalars maps Go types of custom scalars to functions that set v,
+	// a value of the Go type, to the JSON value of a scalar, as decoded
+	// by encoding/json with UseNumber. Null sets v to its zero value
+	// without calling the function.
+	Scalars map[reflect.Type]func(value any, v reflect.Value) error
+}
+
+// UnmarshalGraphQLOptions is like UnmarshalGraphQL, but decodes
+// the data as configured by opts.
+func UnmarshalGraphQLOptions(data []byte, v any, opts Options) error {
 	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.UseNumber()
-	err := (&decoder{tokenizer: dec}).Decode(v)
+	err := (&decoder{tokenizer: dec, opts: opts}).Decode(v)
 	if err != nil {
 		return err
 	}
@@ -37,12 +68,33 @@ func UnmarshalGraphQL(data []byte, v any) error {
 	}
 }
 
+// DecodeGraphQL decodes the next JSON value from dec, which is GraphQL
+// response data, into the GraphQL query data structure pointed to by v.
+// dec must have UseNumber set. It's meant for decoding the data
+// while the response is being read.
+//
+// If the value can't be decoded, the rest of it is skipped, if possible,
+// so that dec can be used to read what follows it in the response.
+func DecodeGraphQL(dec *json.Decoder, v any, opts Options) error {
+	d := &decoder{tokenizer: dec, opts: opts}
+	err := d.Decode(v)
+	if err != nil {
+		for d.depth > 0 {
+			if _, err := d.token(); err != nil {
+				break
+			}
+		}
+	}
+	return err
+}
+
 // decoder is a JSON decoder that performs custom unmarshaling behavior
 // for GraphQL query data structures. It's implemented on top of a JSON tokenizer.
 type decoder struct {
 	tokenizer interface {
 		Token() (json.Token, error)
 	}
+	opts Options
 
 	// Stack of what part of input JSON we're in the middle of - objects, arrays.
 	parseState []json.Delim
@@ -54,6 +106,50 @@ type decoder struct {
 	// a single JSON value into multiple GraphQL fragments or embedded structs, so
 	// we keep track of them all.
 	vs [][]reflect.Value
+
+	// Fragments that the d.vs stacks at the same index unmarshal into,
+	// or nil for stacks that aren't for fragments or embedded structs.
+	fragments []*fragment
+
+	// Response path of the value on top of the d.vs stacks.
+	// Elements are object keys (string) and array indices (int).
+	path []any
+
+	// Stack of the number of elements seen so far in arrays we're inside of.
+	lengths []int
+
+	// Number of objects and arrays that tokens read so far are inside of.
+	depth int
+}
+
+// fragment describes a GraphQL inline fragment or an embedded struct
+// whose fields are unmarshaled from the fields of the enclosing object.
+type fragment struct {
+	typeCondition string    // Type condition of the inline fragment, if any.
+	parent        *fragment // Enclosing fragment, or a placeholder for the enclosing struct.
+	skip          bool      // Whether the fragment is skipped, because its type condition doesn't match.
+}
+
+// skipped reports whether f or any enclosing fragment is skipped.
+func (f *fragment) skipped() bool {
+	for ; f != nil; f = f.parent {
+		if f.skip {
+			return true
+		}
+	}
+	return false
+}
+
+// token reads the next token from d.tokenizer, keeping track of d.depth.
+func (d *decoder) token() (json.Token, error) {
+	tok, err := d.tokenizer.Token()
+	switch tok {
+	case json.Delim('{'), json.Delim('['):
+		d.depth++
+	case json.Delim('}'), json.Delim(']'):
+		d.depth--
+	}
+	return tok, err
 }
 
 // Decode decodes a single JSON value from d.tokenizer into v.
@@ -63,6 +159,7 @@ func (d *decoder) Decode(v any) error {
 		return fmt.Errorf("cannot decode into non-pointer %T", v)
 	}
 	d.vs = [][]reflect.Value{{rv.Elem()}}
+	d.fragments = []*fragment{nil}
 	return d.decode()
 }
 
@@ -71,18 +168,20 @@ func (d *decoder) decode() error {
 	// The loop invariant is that the top of each d.vs stack
 	// is where we try to unmarshal the next JSON value we see.
 	for len(d.vs) > 0 {
-		tok, err := d.tokenizer.Token()
+		tok, err := d.token()
 		if err == io.EOF {
 			return errors.New("unexpected end of JSON input")
 		} else if err != nil {
 			return err
 		}
 
+		var key string // Key of the value in tok, if it's in an object.
 		switch {
 
 		// Are we inside an object and seeing next key (rather than end of object)?
 		case d.state() == '{' && tok != json.Delim('}'):
-			key, ok := tok.(string)
+			var ok bool
+			key, ok = tok.(string)
 			if !ok {
 				return errors.New("unexpected non-key in JSON input")
 			}
@@ -94,20 +193,39 @@ func (d *decoder) decode() error {
 				}
 				var f reflect.Value
 				if v.Kind() == reflect.Struct {
-					f = fieldByGraphQLName(v, key)
+					f, err = fieldByGraphQLName(v, key)
+					if err != nil {
+						return err
+					}
 					if f.IsValid() {
 						someFieldExist = true
 					}
+					if d.fragments[i].skipped() {
+						// The field exists, but it's not for this type.
+						f = reflect.Value{}
+					}
 				}
 				d.vs[i] = append(d.vs[i], f)
 			}
-			if !someFieldExist {
-				return fmt.Errorf("struct field for %q doesn't exist in any of %v places to unmarshal", key, len(d.vs))
+			d.path = append(d.path, key)
+			if !someFieldExist && key != "__typename" && d.opts.Lenient {
+				// Skip the value, and anything inside of it.
+				d.popAllVs()
+				err := d.skipValue()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !someFieldExist && key != "__typename" {
+				// __typename may be requested only to select inline fragments,
+				// without a struct field for it.
+				return d.error(0, typeOf(reflect.Indirect(d.vs[0][len(d.vs[0])-2])), tok, fmt.Errorf("struct field for %q doesn't exist in any of %v places to unmarshal", key, len(d.vs)))
 			}
 
 			// We've just consumed the current token, which was the key.
 			// Read the next token, which should be the value, and let the rest of code process it.
-			tok, err = d.tokenizer.Token()
+			tok, err = d.token()
 			if err == io.EOF {
 				return errors.New("unexpected end of JSON input")
 			} else if err != nil {
@@ -117,38 +235,136 @@ func (d *decoder) decode() error {
 		// Are we inside an array and seeing next value (rather than end of array)?
 		case d.state() == '[' && tok != json.Delim(']'):
 			someSliceExist := false
+			index := d.lengths[len(d.lengths)-1]
+			d.lengths[len(d.lengths)-1]++
 			for i := range d.vs {
 				v := d.vs[i][len(d.vs[i])-1]
 				if v.Kind() == reflect.Ptr {
 					v = v.Elem()
 				}
 				var f reflect.Value
-				if v.Kind() == reflect.Slice {
+				switch {
+				case v.IsValid() && fields.IsAppender(v.Type()):
+					p := reflect.ValueOf(v.Addr().Interface().(fields.Appender).AppendGraphQL())
+					if p.Kind() != reflect.Ptr || p.IsNil() {
+						d.vs[i] = append(d.vs[i], f)
+						d.path = append(d.path, index)
+						return d.error(i, v.Type(), tok, fmt.Errorf("%v.AppendGraphQL returned %v, want a non-nil pointer", v.Type(), typeOf(p)))
+					}
+					f = p.Elem()
+					someSliceExist = true
+				case v.Kind() == reflect.Slice:
 					v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem()))) // v = append(v, T).
 					f = v.Index(v.Len() - 1)
 					someSliceExist = true
+				case v.Kind() == reflect.Array:
+					if index >= v.Len() {
+						d.vs[i] = append(d.vs[i], f)
+						d.path = append(d.path, index)
+						return d.error(i, v.Type(), tok, fmt.Errorf("array has more elements than Go array type %v can hold", v.Type()))
+					}
+					f = v.Index(index)
+					someSliceExist = true
 				}
 				d.vs[i] = append(d.vs[i], f)
 			}
 			if !someSliceExist {
-				return fmt.Errorf("slice doesn't exist in any of %v places to unmarshal", len(d.vs))
+				return d.error(0, typeOf(d.vs[0][len(d.vs[0])-2]), tok, fmt.Errorf("slice doesn't exist in any of %v places to unmarshal", len(d.vs)))
+			}
+			d.path = append(d.path, index)
+		}
+
+		if (tok == json.Delim('{') || tok == json.Delim('[')) && d.topCapturesValue() {
+			// The Go type to unmarshal into depends on __typename, which may
+			// be anywhere in the object, or the Go value is decoded from
+			// the entire JSON value, so read the entire value first.
+			b, err := d.readValue(tok)
+			if err != nil {
+				return err
+			}
+			for i := range d.vs {
+				v := d.vs[i][len(d.vs[i])-1]
+				if !v.IsValid() {
+					continue
+				}
+				if d.isScalar(v.Type()) {
+					dec := json.NewDecoder(bytes.NewReader(b))
+					dec.UseNumber()
+					var value any
+					err := dec.Decode(&value)
+					if err == nil {
+						err = d.decodeScalar(value, v)
+					}
+					if err != nil {
+						return d.error(i, v.Type(), tok, err)
+					}
+					continue
+				}
+				if fields.CapturesValue(v.Type()) {
+					err := json.Unmarshal(b, v.Addr().Interface())
+					if err != nil {
+						return d.error(i, v.Type(), tok, err)
+					}
+					continue
+				}
+				err := unmarshalObject(b, v, d.nestedOptions())
+				if e, ok := err.(*DecodeError); ok {
+					// Make the path relative to the entire response.
+					e.Path = joinPath(d.pathString(), e.Path)
+					return e
+				} else if err != nil {
+					return d.error(i, v.Type(), tok, err)
+				}
 			}
+			d.popAllVs()
+			continue
 		}
 
 		switch tok := tok.(type) {
 		case string, json.Number, bool, nil:
 			// Value.
 
+			if tok == nil && len(d.parseState) == 0 {
+				// Null response data, such as when there are errors,
+				// has nothing to decode.
+				d.popAllVs()
+				continue
+			}
+
+			nullStruct := false // Whether null is decoded into a non-pointer struct.
 			for i := range d.vs {
 				v := d.vs[i][len(d.vs[i])-1]
 				if !v.IsValid() {
 					continue
 				}
+				if d.isScalar(v.Type()) {
+					err := d.decodeScalar(tok, v)
+					if err != nil {
+						return d.error(i, v.Type(), tok, err)
+					}
+					continue
+				}
+				if tok == nil && v.Kind() == reflect.Struct && !hasUnmarshaler(v.Type()) {
+					// encoding/json would leave the struct unchanged, which
+					// makes null indistinguishable from an empty object.
+					if d.opts.DisallowNullStructs {
+						return d.error(i, v.Type(), tok, errors.New("null can't be decoded into a struct that isn't behind a pointer"))
+					}
+					zero(v)
+					nullStruct = true
+					continue
+				}
 				err := unmarshalValue(tok, v)
 				if err != nil {
-					return err
+					return d.error(i, v.Type(), tok, err)
 				}
 			}
+			if nullStruct && d.opts.RecordNull != nil {
+				d.opts.RecordNull(d.pathString())
+			}
+			if typename, ok := tok.(string); ok && key == "__typename" {
+				d.selectFragments(typename)
+			}
 			d.popAllVs()
 
 		case json.Delim:
@@ -158,10 +374,17 @@ func (d *decoder) decode() error {
 
 				d.pushState(tok)
 
-				frontier := make([]reflect.Value, len(d.vs)) // Places to look for GraphQL fragments/embedded structs.
+				frontier := make([]reflect.Value, len(d.vs))      // Places to look for GraphQL fragments/embedded structs.
+				frontierFragments := make([]*fragment, len(d.vs)) // Fragments that the places in frontier are in.
 				for i := range d.vs {
 					v := d.vs[i][len(d.vs[i])-1]
+					if v.IsValid() && fields.IsAppender(fields.Indirect(v.Type())) {
+						// The object's keys don't match anything in a collection,
+						// so it would be decoded as an empty one.
+						return d.error(i, v.Type(), tok, fmt.Errorf("cannot decode object into collection type %v, it can only be decoded from a list", fields.Indirect(v.Type())))
+					}
 					frontier[i] = v
+					frontierFragments[i] = new(fragment)
 					// TODO: Do this recursively or not? Add a test case if needed.
 					if v.Kind() == reflect.Ptr && v.IsNil() {
 						v.Set(reflect.New(v.Type().Elem())) // v = new(T).
@@ -170,19 +393,31 @@ func (d *decoder) decode() error {
 				// Find GraphQL fragments/embedded structs recursively, adding to frontier
 				// as new ones are discovered and exploring them further.
 				for len(frontier) > 0 {
-					v := frontier[0]
-					frontier = frontier[1:]
+					v, parent := frontier[0], frontierFragments[0]
+					frontier, frontierFragments = frontier[1:], frontierFragments[1:]
 					if v.Kind() == reflect.Ptr {
 						v = v.Elem()
 					}
 					if v.Kind() != reflect.Struct {
 						continue
 					}
-					for i := 0; i < v.NumField(); i++ {
-						if isGraphQLFragment(v.Type().Field(i)) || v.Type().Field(i).Anonymous {
+					fs, err := fields.Of(v.Type())
+					if err != nil {
+						return err
+					}
+					for _, f := range fs {
+						if f.Conditional {
+							// Fields that aren't in the response, because they're
+							// skipped by a directive, are left at their zero value.
+							zero(v.Field(f.Index))
+						}
+						if f.Fragment || v.Type().Field(f.Index).Anonymous {
 							// Add GraphQL fragment or embedded struct.
-							d.vs = append(d.vs, []reflect.Value{v.Field(i)})
-							frontier = append(frontier, v.Field(i))
+							frag := &fragment{typeCondition: f.TypeCondition, parent: parent}
+							d.vs = append(d.vs, []reflect.Value{v.Field(f.Index)})
+							d.fragments = append(d.fragments, frag)
+							frontier = append(frontier, v.Field(f.Index))
+							frontierFragments = append(frontierFragments, frag)
 						}
 					}
 				}
@@ -190,27 +425,35 @@ func (d *decoder) decode() error {
 				// Start of array.
 
 				d.pushState(tok)
+				d.lengths = append(d.lengths, 0)
 
 				for i := range d.vs {
 					v := d.vs[i][len(d.vs[i])-1]
-					// TODO: Confirm this is needed, write a test case.
-					//if v.Kind() == reflect.Ptr && v.IsNil() {
-					//	v.Set(reflect.New(v.Type().Elem())) // v = new(T).
-					//}
+					if v.Kind() == reflect.Ptr && v.IsNil() {
+						v.Set(reflect.New(v.Type().Elem())) // v = new(T).
+					}
 
-					// Reset slice to empty (in case it had non-zero initial value).
+					// Reset slice to empty, and arrays and custom collections
+					// to zero (in case they had non-zero initial value).
 					if v.Kind() == reflect.Ptr {
 						v = v.Elem()
 					}
-					if v.Kind() != reflect.Slice {
-						continue
+					switch {
+					case v.IsValid() && fields.IsAppender(v.Type()):
+						zero(v)
+					case v.Kind() == reflect.Slice:
+						v.Set(reflect.MakeSlice(v.Type(), 0, 0)) // v = make(T, 0, 0).
+					case v.Kind() == reflect.Array:
+						zero(v)
 					}
-					v.Set(reflect.MakeSlice(v.Type(), 0, 0)) // v = make(T, 0, 0).
 				}
 			case '}', ']':
 				// End of object or array.
 				d.popAllVs()
 				d.popState()
+				if tok == ']' {
+					d.lengths = d.lengths[:len(d.lengths)-1]
+				}
 			default:
 				return errors.New("unexpected delimiter in JSON input")
 			}
@@ -221,6 +464,156 @@ func (d *decoder) decode() error {
 	return nil
 }
 
+// DecodeError is an error that occurred while decoding GraphQL response data,
+// along with where in the response and into what Go value it occurred.
+type DecodeError struct {
+	// Path is the response path of the value being decoded,
+	// e.g., "repository.issues.nodes[3].author.login".
+	// It's empty for the response data itself.
+	Path string
+
+	// Field is the name of the Go struct field being decoded into,
+	// if any, and Type is the Go type being decoded into.
+	Field string
+	Type  reflect.Type
+
+	// Token is the offending JSON token, in JSON form, e.g., `"gopher"`, `42` or `{`.
+	Token string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("decoding ")
+	if e.Path == "" {
+		sb.WriteString("response data")
+	} else {
+		sb.WriteString(e.Path)
+	}
+	// Anonymous struct types are left out, they're too long to be useful.
+	switch {
+	case e.Field != "" && (e.Type == nil || anonymousStruct(e.Type)):
+		fmt.Fprintf(&sb, " into Go struct field %s", e.Field)
+	case e.Field != "":
+		fmt.Fprintf(&sb, " into Go struct field %s of type %v", e.Field, e.Type)
+	case e.Type != nil && !anonymousStruct(e.Type):
+		fmt.Fprintf(&sb, " into Go type %v", e.Type)
+	}
+	if e.Token != "" {
+		fmt.Fprintf(&sb, " at token %s", e.Token)
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// anonymousStruct reports whether t is an anonymous struct type,
+// or a pointer, slice, array or map type with one as its element.
+func anonymousStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t.Name() == ""
+}
+
+// error returns a DecodeError for err, which occurred while decoding tok
+// into value of type t on top of the d.vs stack at index i.
+func (d *decoder) error(i int, t reflect.Type, tok json.Token, err error) *DecodeError {
+	return &DecodeError{Path: d.pathString(), Field: d.fieldName(i), Type: t, Token: tokenString(tok), Err: err}
+}
+
+// pathString returns d.path formatted as a response path.
+func (d *decoder) pathString() string {
+	var sb strings.Builder
+	for _, elem := range d.path {
+		switch elem := elem.(type) {
+		case string:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(elem)
+		case int:
+			fmt.Fprintf(&sb, "[%d]", elem)
+		}
+	}
+	return sb.String()
+}
+
+// fieldName returns the name of the Go struct field on top of the d.vs stack
+// at index i, or the empty string if it's not a struct field.
+func (d *decoder) fieldName(i int) string {
+	s := d.vs[i]
+	if len(s) < 2 || len(d.path) == 0 {
+		return ""
+	}
+	key, ok := d.path[len(d.path)-1].(string)
+	if !ok {
+		return ""
+	}
+	v := s[len(s)-2]
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	fs, err := fields.Of(v.Type())
+	if err != nil {
+		return ""
+	}
+	for _, f := range fs {
+		if f.Exported && f.HasName(key) {
+			return v.Type().Field(f.Index).Name
+		}
+	}
+	return ""
+}
+
+// nestedOptions returns d.opts for decoding the value on top of the d.vs
+// stacks separately, with paths passed to RecordNull made relative to
+// the entire response.
+func (d *decoder) nestedOptions() Options {
+	opts := d.opts
+	if record := d.opts.RecordNull; record != nil {
+		prefix := d.pathString()
+		opts.RecordNull = func(path string) { record(joinPath(prefix, path)) }
+	}
+	return opts
+}
+
+// joinPath returns response path path, which is relative to
+// response path prefix, made relative to the entire response.
+func joinPath(prefix, path string) string {
+	switch {
+	case path == "":
+		return prefix
+	case prefix != "" && path[0] != '[':
+		return prefix + "." + path
+	default:
+		return prefix + path
+	}
+}
+
+// tokenString returns tok in JSON form.
+func tokenString(tok json.Token) string {
+	switch tok := tok.(type) {
+	case json.Delim:
+		return tok.String()
+	case json.Number:
+		return tok.String()
+	default:
+		b, err := json.Marshal(tok)
+		if err != nil {
+			return fmt.Sprint(tok)
+		}
+		return string(b)
+	}
+}
+
 // pushState pushes a new parse state s onto the stack.
 func (d *decoder) pushState(s json.Delim) {
 	d.parseState = append(d.parseState, s)
@@ -243,68 +636,372 @@ func (d *decoder) state() json.Delim {
 // popAllVs pops from all d.vs stacks, keeping only non-empty ones.
 func (d *decoder) popAllVs() {
 	var nonEmpty [][]reflect.Value
+	var nonEmptyFragments []*fragment
 	for i := range d.vs {
 		d.vs[i] = d.vs[i][:len(d.vs[i])-1]
 		if len(d.vs[i]) > 0 {
 			nonEmpty = append(nonEmpty, d.vs[i])
+			nonEmptyFragments = append(nonEmptyFragments, d.fragments[i])
 		}
 	}
 	d.vs = nonEmpty
+	d.fragments = nonEmptyFragments
+	if len(d.path) > 0 {
+		d.path = d.path[:len(d.path)-1]
+	}
 }
 
-// fieldByGraphQLName returns an exported struct field of struct v
-// that matches GraphQL name, or invalid reflect.Value if none found.
-func fieldByGraphQLName(v reflect.Value, name string) reflect.Value {
-	for i := 0; i < v.NumField(); i++ {
-		if v.Type().Field(i).PkgPath != "" {
-			// Skip unexported field.
-			continue
+// selectFragments skips the inline fragments of the current object whose
+// type condition doesn't match its __typename, clearing anything already
+// unmarshaled into them, so fields of skipped fragments are left unpopulated.
+// It's called with the __typename value on top of the d.vs stacks.
+//
+// Type conditions may name interface types that typename implements,
+// which can't be told apart without a schema. So fragments are skipped
+// only if a sibling fragment (one directly inside the same struct or
+// fragment) has a type condition that matches typename exactly.
+func (d *decoder) selectFragments(typename string) {
+	// Stacks for fragments of the current object contain
+	// the fragment and the __typename value.
+	matched := make(map[*fragment]bool) // Parents with a child fragment that matches typename.
+	for i, f := range d.fragments {
+		if f != nil && len(d.vs[i]) == 2 && f.typeCondition == typename {
+			matched[f.parent] = true
 		}
-		if hasGraphQLName(v.Type().Field(i), name) {
-			return v.Field(i)
+	}
+	if len(matched) == 0 {
+		return
+	}
+	for i, f := range d.fragments {
+		if f != nil && len(d.vs[i]) == 2 && f.typeCondition != "" && f.typeCondition != typename && matched[f.parent] {
+			f.skip = true
+		}
+	}
+	for i, f := range d.fragments {
+		if f != nil && len(d.vs[i]) == 2 && f.skipped() {
+			zero(d.vs[i][0])
 		}
 	}
-	return reflect.Value{}
 }
 
-// hasGraphQLName reports whether struct field f has GraphQL name.
-func hasGraphQLName(f reflect.StructField, name string) bool {
-	value, ok := f.Tag.Lookup("graphql")
-	if !ok {
-		// TODO: caseconv package is relatively slow. Optimize it, then consider using it here.
-		//return caseconv.MixedCapsToLowerCamelCase(f.Name) == name
-		return strings.EqualFold(f.Name, name)
+// zero sets v to its zero value. Unlike v.Set, it works for
+// embedded structs obtained by the use of unexported struct fields.
+func zero(v reflect.Value) {
+	switch {
+	case v.CanSet():
+		v.Set(reflect.Zero(v.Type()))
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			zero(v.Field(i))
+		}
 	}
-	value = strings.TrimSpace(value) // TODO: Parse better.
-	if strings.HasPrefix(value, "...") {
-		// GraphQL fragment. It doesn't have a name.
+}
+
+// topCapturesValue reports whether the top of any d.vs stack is
+// an interface with possible types registered, or a value that's
+// decoded from an entire JSON value, as reported by fields.CapturesValue.
+func (d *decoder) topCapturesValue() bool {
+	for i := range d.vs {
+		v := d.vs[i][len(d.vs[i])-1]
+		if !v.IsValid() {
+			continue
+		}
+		if v.Kind() == reflect.Interface && fields.PossibleTypes(v.Type()) != nil || fields.CapturesValue(v.Type()) || d.isScalar(v.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScalar reports whether t is the Go type of a custom scalar
+// in d.opts.Scalars, or a pointer to one.
+func (d *decoder) isScalar(t reflect.Type) bool {
+	if len(d.opts.Scalars) == 0 {
 		return false
 	}
-	// Cut off anything that follows the field name,
-	// such as field arguments, aliases, directives.
-	if i := strings.IndexAny(value, "(:@"); i != -1 {
-		value = value[:i]
+	for {
+		if _, ok := d.opts.Scalars[t]; ok {
+			return true
+		}
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
 	}
-	return strings.TrimSpace(value) == name
 }
 
-// isGraphQLFragment reports whether struct field f is a GraphQL fragment.
-func isGraphQLFragment(f reflect.StructField) bool {
-	value, ok := f.Tag.Lookup("graphql")
-	if !ok {
-		return false
+// decodeScalar sets v, which has a type that isScalar reports true for,
+// to the JSON value of a custom scalar, allocating pointers as needed.
+func (d *decoder) decodeScalar(value any, v reflect.Value) error {
+	for {
+		if decode, ok := d.opts.Scalars[v.Type()]; ok && value != nil {
+			return decode(value, v)
+		} else if ok || value == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem())) // v = new(T).
+		}
+		v = v.Elem()
 	}
-	value = strings.TrimSpace(value) // TODO: Parse better.
-	return strings.HasPrefix(value, "...")
+}
+
+// readValue reads the rest of a JSON value that starts with tok
+// from d.tokenizer, and returns its JSON encoding.
+func (d *decoder) readValue(tok json.Token) ([]byte, error) {
+	var buf bytes.Buffer
+	var (
+		delims []json.Delim // Stack of objects and arrays we're inside of.
+		counts []int        // Number of tokens read so far in each of delims.
+	)
+	for {
+		if n := len(delims); n > 0 && tok != json.Delim('}') && tok != json.Delim(']') {
+			switch {
+			case delims[n-1] == '{' && counts[n-1]%2 == 1:
+				buf.WriteByte(':')
+			case counts[n-1] > 0:
+				buf.WriteByte(',')
+			}
+			counts[n-1]++
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			buf.WriteRune(rune(tok))
+			switch tok {
+			case '{', '[':
+				delims = append(delims, tok)
+				counts = append(counts, 0)
+			case '}', ']':
+				delims = delims[:len(delims)-1]
+				counts = counts[:len(counts)-1]
+			}
+		case json.Number:
+			buf.WriteString(tok.String())
+		default:
+			b, err := json.Marshal(tok)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		if len(delims) == 0 {
+			return buf.Bytes(), nil
+		}
+		var err error
+		tok, err = d.token()
+		if err == io.EOF {
+			return nil, errors.New("unexpected end of JSON input")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// skipValue reads the next JSON value from d.tokenizer and discards it.
+func (d *decoder) skipValue() error {
+	depth := 0
+	for {
+		tok, err := d.token()
+		if err == io.EOF {
+			return errors.New("unexpected end of JSON input")
+		} else if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// unmarshalObject unmarshals the JSON-encoded object b into v. If v is an
+// interface with possible types registered, a value of the possible type
+// that the __typename in b maps to is allocated and unmarshaled into.
+func unmarshalObject(b []byte, v reflect.Value, opts Options) error {
+	if v.Kind() == reflect.Interface {
+		if pts := fields.PossibleTypes(v.Type()); pts != nil {
+			var object struct {
+				Typename *string `json:"__typename"`
+			}
+			err := json.Unmarshal(b, &object)
+			if err != nil {
+				return err
+			}
+			if object.Typename == nil {
+				return fmt.Errorf("__typename is required to unmarshal into interface type %v", v.Type())
+			}
+			for _, pt := range pts {
+				if pt.Name != *object.Typename {
+					continue
+				}
+				p := reflect.New(fields.Indirect(pt.Type))
+				err := unmarshalObject(b, p.Elem(), opts)
+				if err != nil {
+					return err
+				}
+				if pt.Type.Kind() == reflect.Ptr {
+					v.Set(p)
+				} else {
+					v.Set(p.Elem())
+				}
+				return nil
+			}
+			return fmt.Errorf("__typename %q isn't a registered possible type of interface type %v", *object.Typename, v.Type())
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	d := &decoder{tokenizer: dec, opts: opts, vs: [][]reflect.Value{{v}}, fragments: []*fragment{nil}}
+	return d.decode()
+}
+
+// typeOf returns the type of v, or nil if v is invalid.
+func typeOf(v reflect.Value) reflect.Type {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Type()
+}
+
+// fieldByGraphQLName returns an exported struct field of struct v
+// that matches GraphQL name, or invalid reflect.Value if none found.
+func fieldByGraphQLName(v reflect.Value, name string) (reflect.Value, error) {
+	fs, err := fields.Of(v.Type())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	for _, f := range fs {
+		if !f.Exported {
+			// Skip unexported field.
+			continue
+		}
+		if f.HasName(name) {
+			return v.Field(f.Index), nil
+		}
+	}
+	return reflect.Value{}, nil
 }
 
 // unmarshalValue unmarshals JSON value into v.
 // v must be addressable and not obtained by the use of unexported
 // struct fields, otherwise unmarshalValue will panic.
 func unmarshalValue(value json.Token, v reflect.Value) error {
-	b, err := json.Marshal(value) // TODO: Short-circuit (if profiling says it's worth it).
+	if setValue(value, v) {
+		return nil
+	}
+	// Fall back to encoding/json for custom unmarshalers, mismatched kinds
+	// and out of range numbers, so that the behavior and errors are the same.
+	b, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal(b, v.Addr().Interface())
 }
+
+// setValue sets JSON value into v directly, the same way as json.Unmarshal
+// would, if v has a common kind that doesn't implement an unmarshaler
+// interface. It reports whether it did.
+func setValue(value json.Token, v reflect.Value) bool {
+	if hasUnmarshaler(v.Type()) {
+		return false
+	}
+	if value == nil {
+		// Null sets pointers, interfaces, maps and slices to nil,
+		// and leaves other values unchanged.
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if hasUnmarshaler(v.Type().Elem()) {
+			return false
+		}
+		if v.IsNil() {
+			p := reflect.New(v.Type().Elem())
+			if !setValue(value, p.Elem()) {
+				return false
+			}
+			v.Set(p)
+			return true
+		}
+		return setValue(value, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 || !v.IsNil() {
+			return false
+		}
+		switch value := value.(type) {
+		case string:
+			v.Set(reflect.ValueOf(value))
+		case bool:
+			v.Set(reflect.ValueOf(value))
+		case json.Number:
+			f, err := strconv.ParseFloat(string(value), 64)
+			if err != nil {
+				return false
+			}
+			v.Set(reflect.ValueOf(f))
+		}
+		return true
+	}
+	switch value := value.(type) {
+	case string:
+		if v.Kind() != reflect.String {
+			return false
+		}
+		v.SetString(value)
+	case bool:
+		if v.Kind() != reflect.Bool {
+			return false
+		}
+		v.SetBool(value)
+	case json.Number:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil || v.OverflowInt(n) {
+				return false
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n, err := strconv.ParseUint(string(value), 10, 64)
+			if err != nil || v.OverflowUint(n) {
+				return false
+			}
+			v.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(string(value), v.Type().Bits())
+			if err != nil || v.OverflowFloat(f) {
+				return false
+			}
+			v.SetFloat(f)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// hasUnmarshaler reports whether type t or a pointer to it implements
+// an interface that encoding/json uses to unmarshal values.
+func hasUnmarshaler(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PtrTo(t)
+	}
+	return t.Implements(jsonUnmarshaler) || t.Implements(textUnmarshaler)
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
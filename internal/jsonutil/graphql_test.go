@@ -1,11 +1,15 @@
 package jsonutil_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/shurcooL/graphql"
+	"github.com/shurcooL/graphql/internal/fields"
 	"github.com/shurcooL/graphql/internal/jsonutil"
 )
 
@@ -249,7 +253,20 @@ func TestUnmarshalGraphQL_unexportedField(t *testing.T) {
 	if err == nil {
 		t.Fatal("got error: nil, want: non-nil")
 	}
-	if got, want := err.Error(), "struct field for \"foo\" doesn't exist in any of 1 places to unmarshal"; got != want {
+	if got, want := err.Error(), "decoding foo into Go type jsonutil_test.query at token \"foo\": struct field for \"foo\" doesn't exist in any of 1 places to unmarshal"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_invalidTag(t *testing.T) {
+	type query struct {
+		Foo graphql.String `graphql:"foo(bar:)"`
+	}
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"foo": "bar"}`), new(query))
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), `invalid graphql struct tag "foo(bar:)" on struct field jsonutil_test.query.Foo: syntax error at column 9: expected value, found ")"`; got != want {
 		t.Errorf("got error: %v, want: %v", got, want)
 	}
 }
@@ -300,6 +317,47 @@ func TestUnmarshalGraphQL_directives(t *testing.T) {
 	}
 }
 
+func TestUnmarshalGraphQL_skippedDirectives(t *testing.T) {
+	/*
+		query($withDetails: Boolean!) {
+			me {
+				name
+				height @include(if: $withDetails)
+				... on Droid @include(if: $withDetails) {
+					primaryFunction
+				}
+			}
+		}
+	*/
+	type query struct {
+		Me struct {
+			Name   graphql.String
+			Height graphql.Float `graphql:"height @include(if: $withDetails)"`
+			Droid  struct {
+				PrimaryFunction graphql.String
+			} `graphql:"... on Droid @include(if: $withDetails)"`
+		}
+	}
+	// Decode into a previously populated query.
+	var got query
+	got.Me.Name = "R2-D2"
+	got.Me.Height = 1.09
+	got.Me.Droid.PrimaryFunction = "Astromech"
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"me": {
+			"name": "Luke Skywalker"
+		}
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Me.Name = "Luke Skywalker"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
 func TestUnmarshalGraphQL_union(t *testing.T) {
 	/*
 		{
@@ -347,18 +405,73 @@ func TestUnmarshalGraphQL_union(t *testing.T) {
 			},
 			CreatedAt: time.Unix(1498709521, 0).UTC(),
 		},
-		ReopenedEvent: reopenedEvent{
-			Actor: actor{
-				Login: "shurcooL-test",
-			},
-			CreatedAt: time.Unix(1498709521, 0).UTC(),
-		},
+		// ReopenedEvent is left unpopulated, since its type condition doesn't match __typename.
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Error("not equal")
 	}
 }
 
+func TestUnmarshalGraphQL_unionTypename(t *testing.T) {
+	/*
+		{
+			__typename
+			name
+			... on Node {
+				id
+				... on Human {height}
+			}
+			... on Droid {name,primaryFunction}
+		}
+	*/
+	type node struct {
+		ID    graphql.ID
+		Human struct {
+			Height graphql.Float
+		} `graphql:"... on Human"`
+	}
+	type droid struct {
+		Name            graphql.String
+		PrimaryFunction graphql.String
+	}
+	type character struct {
+		Name  graphql.String
+		Node  node `graphql:"... on Node"`
+		droid `graphql:"... on Droid"`
+	}
+	var got []character
+	err := jsonutil.UnmarshalGraphQL([]byte(`[
+		{
+			"name": "R2-D2",
+			"__typename": "Droid",
+			"id": "2001",
+			"primaryFunction": "Astromech"
+		},
+		{
+			"__typename": "Human",
+			"name": "Luke Skywalker",
+			"id": "1000",
+			"height": 1.72
+		}
+	]`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = make([]character, 2)
+	// Node is skipped, because a sibling fragment matches Droid exactly.
+	want[0].Name = "R2-D2"
+	want[0].droid = droid{Name: "R2-D2", PrimaryFunction: "Astromech"}
+	// No fragment matches Human exactly, so all of them are populated.
+	// Inside Node, the Human fragment matches.
+	want[1].Name = "Luke Skywalker"
+	want[1].Node.ID = "1000"
+	want[1].Node.Human.Height = 1.72
+	want[1].droid.Name = "Luke Skywalker"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
 // Issue https://github.com/shurcooL/githubv4/issues/18.
 func TestUnmarshalGraphQL_arrayInsideInlineFragment(t *testing.T) {
 	/*
@@ -426,3 +539,439 @@ func TestUnmarshalGraphQL_arrayInsideInlineFragment(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+type (
+	searchResult interface{ isSearchResult() }
+	issue        struct {
+		Number graphql.Int
+		Title  graphql.String
+	}
+	repository struct {
+		NameWithOwner graphql.String
+	}
+)
+
+func (issue) isSearchResult()       {}
+func (*repository) isSearchResult() {}
+
+func init() {
+	fields.RegisterPossibleTypes(reflect.TypeOf((*searchResult)(nil)).Elem(), []fields.PossibleType{
+		{Name: "Issue", Type: reflect.TypeOf(issue{})},
+		{Name: "Repository", Type: reflect.TypeOf(&repository{})},
+	})
+}
+
+func TestUnmarshalGraphQL_possibleTypes(t *testing.T) {
+	/*
+		{
+			search(query: "graphql", type: ISSUE, first: 3) {
+				__typename
+				... on Issue {number,title}
+				... on Repository {nameWithOwner}
+			}
+		}
+	*/
+	type query struct {
+		Search []searchResult
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"search": [
+			{"__typename": "Issue", "number": 1, "title": "Support \"unions\""},
+			{"nameWithOwner": "shurcooL/graphql", "__typename": "Repository"},
+			null
+		]
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Search: []searchResult{
+			issue{Number: 1, Title: `Support "unions"`},
+			&repository{NameWithOwner: "shurcooL/graphql"},
+			nil,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_possibleTypesError(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `{"search": [{"number": 1}]}`,
+			want: "decoding search[0] into Go type jsonutil_test.searchResult at token {: __typename is required to unmarshal into interface type jsonutil_test.searchResult",
+		},
+		{
+			in:   `{"search": [{"__typename": "User", "login": "gopher"}]}`,
+			want: `decoding search[0] into Go type jsonutil_test.searchResult at token {: __typename "User" isn't a registered possible type of interface type jsonutil_test.searchResult`,
+		},
+		{
+			in:   `{"search": [{"__typename": "Issue", "login": "gopher"}]}`,
+			want: `decoding search[0].login into Go type jsonutil_test.issue at token "login": struct field for "login" doesn't exist in any of 1 places to unmarshal`,
+		},
+	}
+	for _, tc := range tests {
+		var q struct {
+			Search []searchResult
+		}
+		err := jsonutil.UnmarshalGraphQL([]byte(tc.in), &q)
+		if err == nil {
+			t.Errorf("UnmarshalGraphQL(%s): got error: nil, want: %v", tc.in, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("UnmarshalGraphQL(%s):\n got error: %v\nwant error: %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestUnmarshalGraphQL_scalarKinds checks that scalars are unmarshaled
+// into common kinds the same way as by encoding/json, including errors.
+func TestUnmarshalGraphQL_scalarKinds(t *testing.T) {
+	type (
+		myString string
+		myInt8   int8
+	)
+	type query struct {
+		String    string
+		MyString  myString
+		Int       int
+		Int8      myInt8
+		Uint16    uint16
+		Float32   float32
+		Float64   float64
+		Bool      bool
+		Ptr       *int
+		PtrPtr    **string
+		Any       any
+		ID        graphql.ID
+		Time      time.Time
+		TimePtr   *time.Time
+		Slice     []int
+		Unchanged string
+	}
+	tests := []string{
+		`{"string": "a\"b", "myString": "c", "int": -42, "int8": 127, "uint16": 65535, "float32": 1.5, "float64": -2.5e-3, "bool": true}`,
+		`{"ptr": 7, "ptrPtr": "x", "any": 1e2, "id": "MDQ6VXNlcjE=", "time": "2017-06-29T04:12:01Z", "timePtr": "2017-06-29T04:12:01Z"}`,
+		`{"ptr": null, "ptrPtr": null, "any": null, "id": null, "time": null, "timePtr": null, "slice": null, "unchanged": null}`,
+		`{"any": true, "id": 123}`,
+		`{"int": 1.5}`,
+		`{"int8": 128}`,
+		`{"uint16": -1}`,
+		`{"float32": 1e39}`,
+		`{"string": 1}`,
+		`{"bool": "true"}`,
+		`{"int": "1"}`,
+		`{"ptr": true}`,
+		`{"time": "yesterday"}`,
+	}
+	for _, in := range tests {
+		initial := func() query {
+			n, s := 1, "s"
+			ps := &s
+			return query{Ptr: &n, PtrPtr: &ps, Any: "any", Slice: []int{1}, Unchanged: "unchanged"}
+		}
+		got, want := initial(), initial()
+		gotErr := jsonutil.UnmarshalGraphQL([]byte(in), &got)
+		wantErr := json.Unmarshal([]byte(in), &want)
+		if e, ok := gotErr.(*jsonutil.DecodeError); ok {
+			gotErr = e.Err
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UnmarshalGraphQL(%s):\n got: %+v\nwant: %+v", in, got, want)
+		}
+		// Errors differ in whether they mention the struct field, so only their types are compared.
+		if reflect.TypeOf(gotErr) != reflect.TypeOf(wantErr) {
+			t.Errorf("UnmarshalGraphQL(%s):\n got error: %v\nwant error: %v", in, gotErr, wantErr)
+		}
+	}
+}
+
+func TestUnmarshalGraphQL_lenient(t *testing.T) {
+	type query struct {
+		Repository struct {
+			Name   graphql.String
+			Issues []struct {
+				Number graphql.Int
+			}
+			Owner struct {
+				Typename graphql.String `graphql:"__typename"`
+				User     struct {
+					Login graphql.String
+				} `graphql:"... on User"`
+				Organization struct {
+					Login graphql.String
+				} `graphql:"... on Organization"`
+			}
+		}
+	}
+	const in = `{
+		"repository": {
+			"id": "MDEw",
+			"name": "graphql",
+			"issues": [{"number": 1, "labels": {"nodes": [{"name": "bug"}]}}, {"number": 2, "closed": null}],
+			"licenseInfo": {"name": "MIT", "spdx": ["MIT", {"x": [[]]}]},
+			"owner": {"__typename": "User", "login": "shurcooL", "bio": ""}
+		}
+	}`
+	var got query
+	err := jsonutil.UnmarshalGraphQLOptions([]byte(in), &got, jsonutil.Options{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Repository.Name = "graphql"
+	want.Repository.Issues = []struct{ Number graphql.Int }{{1}, {2}}
+	want.Repository.Owner.Typename = "User"
+	want.Repository.Owner.User.Login = "shurcooL"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// Strict decoding is the default.
+	err = jsonutil.UnmarshalGraphQL([]byte(in), new(query))
+	if got, want := fmt.Sprint(err), `decoding repository.id at token "id": struct field for "id" doesn't exist in any of 1 places to unmarshal`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_decodeError(t *testing.T) {
+	var q struct {
+		Repository struct {
+			Issues struct {
+				Nodes []struct {
+					Author *struct {
+						Login graphql.Int
+					}
+				}
+			}
+		}
+	}
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"repository": {"issues": {"nodes": [
+		{"author": {"login": 1}}, {"author": null}, {"author": {"login": 3}}, {"author": {"login": "gopher"}}
+	]}}}`), &q)
+	var e *jsonutil.DecodeError
+	if !errors.As(err, &e) {
+		t.Fatalf("got error: %v, want a *jsonutil.DecodeError", err)
+	}
+	if got, want := e.Path, "repository.issues.nodes[3].author.login"; got != want {
+		t.Errorf("got Path: %q, want: %q", got, want)
+	}
+	if got, want := e.Field, "Login"; got != want {
+		t.Errorf("got Field: %q, want: %q", got, want)
+	}
+	if got, want := e.Type, reflect.TypeOf(graphql.Int(0)); got != want {
+		t.Errorf("got Type: %v, want: %v", got, want)
+	}
+	if got, want := e.Token, `"gopher"`; got != want {
+		t.Errorf("got Token: %s, want: %s", got, want)
+	}
+	if _, ok := e.Err.(*json.UnmarshalTypeError); !ok {
+		t.Errorf("got Err of type %T, want *json.UnmarshalTypeError", e.Err)
+	}
+	if got, want := err.Error(), `decoding repository.issues.nodes[3].author.login into Go struct field Login of type graphql.Int at token "gopher": json: cannot unmarshal string into Go value of type graphql.Int`; got != want {
+		t.Errorf("got error:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_capturedValues(t *testing.T) {
+	type query struct {
+		Viewer struct {
+			Login    graphql.String
+			Status   map[string]any
+			Settings json.RawMessage
+			Metadata any
+			Tags     *[]any
+			Labels   []map[string]string
+		}
+	}
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"viewer": {
+			"login": "gopher",
+			"status": {"emoji": ":wave:", "expiresAt": null, "counts": [1, 2.5], "nested": {"a": true}},
+			"settings": {"theme": "dark", "flags": [{"x": 1}]},
+			"metadata": [{"k": "v"}, 3],
+			"tags": ["a", {"b": []}],
+			"labels": [{"name": "bug"}, {"name": "help wanted"}]
+		}
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Viewer.Login = "gopher"
+	want.Viewer.Status = map[string]any{"emoji": ":wave:", "expiresAt": nil, "counts": []any{1.0, 2.5}, "nested": map[string]any{"a": true}}
+	want.Viewer.Settings = json.RawMessage(`{"theme":"dark","flags":[{"x":1}]}`)
+	want.Viewer.Metadata = []any{map[string]any{"k": "v"}, 3.0}
+	want.Viewer.Tags = &[]any{"a", map[string]any{"b": []any{}}}
+	want.Viewer.Labels = []map[string]string{{"name": "bug"}, {"name": "help wanted"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+// numbers is a custom collection of numbers.
+type numbers struct {
+	sum   int
+	elems []*graphql.Int
+}
+
+func (n *numbers) AppendGraphQL() any {
+	if len(n.elems) > 0 {
+		n.sum += int(*n.elems[len(n.elems)-1])
+	}
+	n.elems = append(n.elems, new(graphql.Int))
+	return n.elems[len(n.elems)-1]
+}
+
+func TestUnmarshalGraphQL_collections(t *testing.T) {
+	type query struct {
+		Array  [3]graphql.Int
+		Short  [3]graphql.Int
+		Nested [2][2]graphql.String
+		Custom numbers
+		Ptr    *numbers
+	}
+	got := query{Short: [3]graphql.Int{7, 8, 9}, Custom: numbers{sum: 100}}
+	err := jsonutil.UnmarshalGraphQL([]byte(`{
+		"array": [1, 2, 3],
+		"short": [1],
+		"nested": [["a", "b"], ["c"]],
+		"custom": [1, 2, 3],
+		"ptr": [4]
+	}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [3]graphql.Int{1, 2, 3}; got.Array != want {
+		t.Errorf("got Array: %v, want: %v", got.Array, want)
+	}
+	if want := [3]graphql.Int{1, 0, 0}; got.Short != want {
+		t.Errorf("got Short: %v, want: %v", got.Short, want)
+	}
+	if want := [2][2]graphql.String{{"a", "b"}, {"c", ""}}; got.Nested != want {
+		t.Errorf("got Nested: %v, want: %v", got.Nested, want)
+	}
+	if got.Custom.sum != 3 || len(got.Custom.elems) != 3 || *got.Custom.elems[2] != 3 {
+		t.Errorf("got Custom: sum %v, %v elements, want: sum 3, 3 elements ending with 3", got.Custom.sum, len(got.Custom.elems))
+	}
+	if got.Ptr == nil || len(got.Ptr.elems) != 1 || *got.Ptr.elems[0] != 4 {
+		t.Errorf("got Ptr: %+v, want 1 element 4", got.Ptr)
+	}
+
+	// An object would silently decode as an empty collection.
+	err = jsonutil.UnmarshalGraphQLOptions([]byte(`{"custom": {}}`), new(query), jsonutil.Options{Lenient: true})
+	if got, want := fmt.Sprint(err), `decoding custom into Go struct field Custom of type jsonutil_test.numbers at token {: cannot decode object into collection type jsonutil_test.numbers, it can only be decoded from a list`; got != want {
+		t.Errorf("got error:\n%v\nwant:\n%v", got, want)
+	}
+
+	var q struct {
+		Repository struct {
+			Languages [2]struct {
+				Name graphql.String
+			}
+		}
+	}
+	err = jsonutil.UnmarshalGraphQL([]byte(`{"repository": {"languages": [{"name": "Go"}, {"name": "C"}, {"name": "Shell"}]}}`), &q)
+	if got, want := fmt.Sprint(err), `decoding repository.languages[2] at token {: array has more elements than Go array type [2]struct { Name graphql.String } can hold`; got != want {
+		t.Errorf("got error:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_nullStructs(t *testing.T) {
+	type query struct {
+		Repository struct {
+			Owner struct {
+				Login graphql.String
+			}
+			Issues []struct {
+				Author struct {
+					Login graphql.String
+				}
+			}
+			License *struct {
+				Name graphql.String
+			}
+		}
+	}
+	const in = `{"repository": {
+		"owner": null,
+		"issues": [{"author": {"login": "gopher"}}, {"author": null}],
+		"license": null
+	}}`
+
+	// Nulls zero structs that aren't behind a pointer.
+	var got query
+	got.Repository.Owner.Login = "initial"
+	var nulls []string
+	err := jsonutil.UnmarshalGraphQLOptions([]byte(in), &got, jsonutil.Options{
+		RecordNull: func(path string) { nulls = append(nulls, path) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Repository.Issues = make([]struct {
+		Author struct{ Login graphql.String }
+	}, 2)
+	want.Repository.Issues[0].Author.Login = "gopher"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\n got: %+v\nwant: %+v", got, want)
+	}
+	if want := []string{"repository.owner", "repository.issues[1].author"}; !reflect.DeepEqual(nulls, want) {
+		t.Errorf("got nulls: %q, want: %q", nulls, want)
+	}
+
+	err = jsonutil.UnmarshalGraphQLOptions([]byte(in), new(query), jsonutil.Options{DisallowNullStructs: true})
+	if got, want := fmt.Sprint(err), "decoding repository.owner into Go struct field Owner at token null: null can't be decoded into a struct that isn't behind a pointer"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestUnmarshalGraphQL_scalars(t *testing.T) {
+	// point is a custom scalar whose JSON form is a list of coordinates.
+	type point struct{ x, y json.Number }
+	opts := jsonutil.Options{Scalars: map[reflect.Type]func(any, reflect.Value) error{
+		reflect.TypeOf(point{}): func(value any, v reflect.Value) error {
+			l, ok := value.([]any)
+			if !ok || len(l) != 2 {
+				return fmt.Errorf("want a list of 2 coordinates, not %v", value)
+			}
+			v.Set(reflect.ValueOf(point{x: l[0].(json.Number), y: l[1].(json.Number)}))
+			return nil
+		},
+	}}
+	type query struct {
+		Location point
+		Path     []*point
+		Previous *point
+	}
+	var got query
+	got.Previous = &point{x: "1", y: "1"}
+	err := jsonutil.UnmarshalGraphQLOptions([]byte(`{
+		"location": [1.5, -2],
+		"path": [[0, 0], null, [3, 4]],
+		"previous": null
+	}`), &got, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Location: point{x: "1.5", y: "-2"},
+		Path:     []*point{{x: "0", y: "0"}, nil, {x: "3", y: "4"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("not equal:\n got: %+v\nwant: %+v", got, want)
+	}
+
+	err = jsonutil.UnmarshalGraphQLOptions([]byte(`{"location": "here"}`), new(query), opts)
+	if got, want := fmt.Sprint(err), `decoding location into Go struct field Location of type jsonutil_test.point at token "here": want a list of 2 coordinates, not here`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+// Package language provides a parser for the GraphQL query language,
+// as used in graphql struct tags and in executable documents.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.
+package language
+
+// Document is a GraphQL executable document.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Document.
+type Document struct {
+	Definitions []Definition
+}
+
+// Definition is an *OperationDefinition or *FragmentDefinition.
+type Definition interface {
+	isDefinition()
+}
+
+// OperationDefinition is a GraphQL operation, such as a query.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Operations.
+type OperationDefinition struct {
+	Operation           string // "query", "mutation" or "subscription".
+	Name                string // Empty if the operation is anonymous.
+	VariableDefinitions []*VariableDefinition
+	Directives          []*Directive
+	SelectionSet        []Selection
+	Source              string // Source text of the definition.
+}
+
+// VariableDefinition is the definition of a variable of an operation.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Variables.
+type VariableDefinition struct {
+	Variable     string // Name of the variable, without "$".
+	Type         Type
+	DefaultValue Value // Nil if the variable has no default value.
+	Directives   []*Directive
+}
+
+// FragmentDefinition is a GraphQL named fragment definition.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Fragments.
+type FragmentDefinition struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Source        string // Source text of the definition.
+}
+
+func (*OperationDefinition) isDefinition() {}
+func (*FragmentDefinition) isDefinition()  {}
+
+// Selection is a *Field, *InlineFragment or *FragmentSpread.
+type Selection interface {
+	isSelection()
+}
+
+// Field is a GraphQL field selection.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Fields.
+type Field struct {
+	Alias        string // Empty if the field has no alias.
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+}
+
+// ResponseKey returns the key of the field in a response,
+// which is its alias if it has one, or its name otherwise.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// InlineFragment is a GraphQL inline fragment.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Inline-Fragments.
+type InlineFragment struct {
+	TypeCondition string // Empty if the fragment has no type condition.
+	Directives    []*Directive
+	SelectionSet  []Selection
+}
+
+// FragmentSpread is a GraphQL fragment spread, such as ...UserFields.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Fragments.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+func (*Field) isSelection()          {}
+func (*InlineFragment) isSelection() {}
+func (*FragmentSpread) isSelection() {}
+
+// Argument is a GraphQL argument of a field or directive.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Arguments.
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Directive is a GraphQL directive, such as @include(if: $x).
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Directives.
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+// Value is a GraphQL input value. It's one of *Variable, *IntValue,
+// *FloatValue, *StringValue, *BooleanValue, *NullValue, *EnumValue,
+// *ListValue or *ObjectValue.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Input-Values.
+type Value interface {
+	isValue()
+}
+
+type (
+	// Variable is a reference to a GraphQL variable, such as $id.
+	Variable struct{ Name string }
+
+	// IntValue is an int value. Raw is its source text.
+	IntValue struct{ Raw string }
+
+	// FloatValue is a float value. Raw is its source text.
+	FloatValue struct{ Raw string }
+
+	// StringValue is a string value. Value is the string after unescaping.
+	StringValue struct{ Value string }
+
+	// BooleanValue is a boolean value.
+	BooleanValue struct{ Value bool }
+
+	// NullValue is the null value.
+	NullValue struct{}
+
+	// EnumValue is an enum value, such as OPEN.
+	EnumValue struct{ Name string }
+
+	// ListValue is a list value, such as [1, 2].
+	ListValue struct{ Values []Value }
+
+	// ObjectValue is an input object value, such as {a: 1}.
+	ObjectValue struct{ Fields []*ObjectField }
+)
+
+// ObjectField is a single field of an input object value.
+type ObjectField struct {
+	Name  string
+	Value Value
+}
+
+func (*Variable) isValue()     {}
+func (*IntValue) isValue()     {}
+func (*FloatValue) isValue()   {}
+func (*StringValue) isValue()  {}
+func (*BooleanValue) isValue() {}
+func (*NullValue) isValue()    {}
+func (*EnumValue) isValue()    {}
+func (*ListValue) isValue()    {}
+func (*ObjectValue) isValue()  {}
+
+// Type is a GraphQL type reference, such as [String!]!.
+// It's one of *NamedType, *ListType or *NonNullType.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Type-References.
+type Type interface {
+	isType()
+}
+
+type (
+	// NamedType is a named type, such as String.
+	NamedType struct{ Name string }
+
+	// ListType is a list type, such as [String].
+	ListType struct{ Type Type }
+
+	// NonNullType is a non-null type, such as String!.
+	NonNullType struct{ Type Type }
+)
+
+func (*NamedType) isType()   {}
+func (*ListType) isType()    {}
+func (*NonNullType) isType() {}
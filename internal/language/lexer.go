@@ -0,0 +1,333 @@
+package language
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind uint8
+
+const (
+	eof tokenKind = iota
+	punctuator
+	name
+	intValue
+	floatValue
+	stringValue
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case eof:
+		return "end of input"
+	case punctuator:
+		return "punctuator"
+	case name:
+		return "name"
+	case intValue:
+		return "int value"
+	case floatValue:
+		return "float value"
+	case stringValue:
+		return "string value"
+	default:
+		return fmt.Sprintf("tokenKind(%d)", k)
+	}
+}
+
+// token is a lexical token of GraphQL source text.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Source-Text.Lexical-Tokens.
+type token struct {
+	kind  tokenKind
+	value string // Punctuator or name text, raw number text, or string value after unescaping.
+	pos   int    // Byte offset of the token in source text.
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case eof:
+		return t.kind.String()
+	case stringValue:
+		return strconv.Quote(t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// lexer splits GraphQL source text into tokens.
+// It skips ignored tokens: white space, line terminators,
+// comments, commas and the Unicode BOM.
+type lexer struct {
+	src string
+	pos int // Byte offset of the next unread character.
+
+	// noComments makes comments be an error. Struct tags are written
+	// into documents as is, where a comment would hide what follows it.
+	noComments bool
+}
+
+// next scans the next token.
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: eof, pos: l.pos}, nil
+	}
+	start := l.pos
+	switch c := l.src[l.pos]; {
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, l.errorf(start, "unexpected %q, did you mean \"...\"?", c)
+		}
+		l.pos += 3
+		return token{kind: punctuator, value: "...", pos: start}, nil
+	case c == '#':
+		// Comments are skipped unless l.noComments is set.
+		return token{}, l.errorf(start, "comments aren't allowed in graphql struct tags")
+	case strings.IndexByte("!$&()=:@[]{|}", c) != -1:
+		l.pos++
+		return token{kind: punctuator, value: l.src[start:l.pos], pos: start}, nil
+	case c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z':
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: name, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || '0' <= c && c <= '9':
+		return l.number()
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString()
+		}
+		return l.string()
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return token{}, l.errorf(start, "unexpected character %q", r)
+	}
+}
+
+// skipIgnored skips over ignored tokens.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.Source-Text.Ignored-Tokens.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#' && !l.noComments:
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+// number scans an int or float value.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Int-Value.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := intValue
+	if l.peek() == '-' {
+		l.pos++
+	}
+	switch {
+	case l.peek() == '0':
+		l.pos++
+		if isDigit(l.peek()) {
+			return token{}, l.errorf(l.pos, "invalid number, unexpected digit after 0")
+		}
+	case isDigit(l.peek()):
+		l.digits()
+	default:
+		return token{}, l.errorf(l.pos, "invalid number, expected digit")
+	}
+	if l.peek() == '.' {
+		kind = floatValue
+		l.pos++
+		if !isDigit(l.peek()) {
+			return token{}, l.errorf(l.pos, "invalid number, expected digit after \".\"")
+		}
+		l.digits()
+	}
+	if c := l.peek(); c == 'e' || c == 'E' {
+		kind = floatValue
+		l.pos++
+		if c := l.peek(); c == '+' || c == '-' {
+			l.pos++
+		}
+		if !isDigit(l.peek()) {
+			return token{}, l.errorf(l.pos, "invalid number, expected digit in exponent")
+		}
+		l.digits()
+	}
+	// The specification doesn't allow a name to directly follow a number,
+	// but minified queries such as "comments(first:1after:$cursor)" are
+	// common and accepted by servers, so only a "." is rejected here.
+	if c := l.peek(); c == '.' {
+		return token{}, l.errorf(l.pos, "invalid number, unexpected %q", c)
+	}
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) digits() {
+	for isDigit(l.peek()) {
+		l.pos++
+	}
+}
+
+// string scans a string value, unescaping it.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-String-Value.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++ // Opening quote.
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf(start, "unterminated string")
+		}
+		switch c := l.src[l.pos]; c {
+		case '"':
+			l.pos++
+			return token{kind: stringValue, value: sb.String(), pos: start}, nil
+		case '\n', '\r':
+			return token{}, l.errorf(start, "unterminated string")
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				return token{}, l.errorf(start, "unterminated string")
+			}
+			switch e := l.src[l.pos]; e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+5 > len(l.src) {
+					return token{}, l.errorf(l.pos-1, "invalid unicode escape sequence")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos+1:l.pos+5], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(l.pos-1, "invalid unicode escape sequence %q", l.src[l.pos-1:l.pos+5])
+				}
+				sb.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				return token{}, l.errorf(l.pos-1, "invalid escape sequence %q", l.src[l.pos-1:l.pos+1])
+			}
+			l.pos++
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// blockString scans a block string value, applying BlockStringValue to it.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-String-Value.
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	l.pos += 3 // Opening quotes.
+	var sb strings.Builder
+	for {
+		switch {
+		case l.pos >= len(l.src):
+			return token{}, l.errorf(start, "unterminated block string")
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			sb.WriteString(`"""`)
+			l.pos += 4
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: stringValue, value: blockStringValue(sb.String()), pos: start}, nil
+		default:
+			sb.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+	}
+}
+
+// blockStringValue removes common indentation and
+// leading and trailing blank lines from raw block string.
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(raw), "\n")
+	commonIndent := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (commonIndent == -1 || indent < commonIndent) {
+			commonIndent = indent
+		}
+	}
+	if commonIndent != -1 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= commonIndent {
+				lines[i] = lines[i][commonIndent:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// peek returns the next unread character, or 0 at end of input.
+func (l *lexer) peek() byte {
+	if l.pos >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos]
+}
+
+// errorf returns a SyntaxError at byte offset pos.
+func (l *lexer) errorf(pos int, format string, a ...any) error {
+	return &SyntaxError{Source: l.src, Offset: pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isNameContinue(c byte) bool {
+	return c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || isDigit(c)
+}
+
+// SyntaxError describes a syntax error in GraphQL source text.
+type SyntaxError struct {
+	Source string // GraphQL source text.
+	Offset int    // Byte offset in Source where the error occurred.
+	Msg    string // Description of the error.
+}
+
+func (e *SyntaxError) Error() string {
+	line, col := 1, 1
+	for _, r := range e.Source[:e.Offset] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	if line == 1 {
+		return fmt.Sprintf("syntax error at column %d: %s", col, e.Msg)
+	}
+	return fmt.Sprintf("syntax error at line %d, column %d: %s", line, col, e.Msg)
+}
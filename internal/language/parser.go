@@ -0,0 +1,553 @@
+package language
+
+import "fmt"
+
+// ParseTag parses the value of a graphql struct tag, which is either
+// a field, such as "alias: name(arg: 1) @skip(if: $x)", or an inline
+// fragment without a selection set, such as "... on User".
+// A field may have a selection set, such as "viewer{login}",
+// which can't contain named fragment spreads. Comments aren't allowed,
+// because tags are written into documents as is.
+func ParseTag(tag string) (Selection, error) {
+	p := &parser{lex: lexer{src: tag, noComments: true}, tag: true}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	var sel Selection
+	if p.peek("...") {
+		sel, err = p.parseInlineFragment()
+	} else {
+		var f *Field
+		f, err = p.parseField()
+		if err == nil && p.peek("{") {
+			f.SelectionSet, err = p.parseSelectionSet()
+		}
+		sel = f
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != eof {
+		return nil, p.unexpected()
+	}
+	return sel, nil
+}
+
+// ParseDocument parses a GraphQL executable document, made of
+// operations and fragment definitions.
+func ParseDocument(src string) (*Document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := new(Document)
+	for p.tok.kind != eof {
+		var def Definition
+		switch {
+		case p.peek("{"):
+			def, err = p.parseOperationDefinition()
+		case p.tok.kind == name && p.tok.value == "fragment":
+			def, err = p.parseFragmentDefinition()
+		case p.tok.kind == name && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			def, err = p.parseOperationDefinition()
+		default:
+			return nil, p.errorf("expected operation or fragment definition, found %v", p.tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc.Definitions = append(doc.Definitions, def)
+	}
+	if len(doc.Definitions) == 0 {
+		return nil, p.errorf("expected operation or fragment definition, found %v", p.tok)
+	}
+	return doc, nil
+}
+
+// ParseType parses a GraphQL type reference, such as "[String!]!".
+func ParseType(typ string) (Type, error) {
+	p, err := newParser(typ)
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != eof {
+		return nil, p.unexpected()
+	}
+	return t, nil
+}
+
+// parser is a recursive descent parser for GraphQL source text.
+type parser struct {
+	lex lexer
+	tok token // Current token.
+	end int   // Byte offset of the end of the last selection set.
+	tag bool  // Whether a struct tag is parsed, where named fragment spreads aren't supported.
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: lexer{src: src}}
+	return p, p.advance()
+}
+
+// advance scans the next token into p.tok.
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is punctuator s.
+func (p *parser) peek(s string) bool {
+	return p.tok.kind == punctuator && p.tok.value == s
+}
+
+// expect consumes punctuator s, or returns an error if it's not the current token.
+func (p *parser) expect(s string) error {
+	if !p.peek(s) {
+		return p.errorf("expected %q, found %v", s, p.tok)
+	}
+	return p.advance()
+}
+
+// skip consumes punctuator s if it's the current token, reporting whether it did.
+func (p *parser) skip(s string) (bool, error) {
+	if !p.peek(s) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// parseName consumes a name.
+func (p *parser) parseName() (string, error) {
+	if p.tok.kind != name {
+		return "", p.errorf("expected name, found %v", p.tok)
+	}
+	n := p.tok.value
+	return n, p.advance()
+}
+
+// parseOperationDefinition parses an operation definition.
+//
+//	OperationDefinition : OperationType Name? VariableDefinitions? Directives? SelectionSet | SelectionSet
+func (p *parser) parseOperationDefinition() (*OperationDefinition, error) {
+	start := p.tok.pos
+	op := &OperationDefinition{Operation: "query"}
+	if !p.peek("{") {
+		op.Operation = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == name {
+			op.Name = p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if op.VariableDefinitions, err = p.parseVariableDefinitions(); err != nil {
+			return nil, err
+		}
+		if op.Directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if op.SelectionSet, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	op.Source = p.lex.src[start:p.end]
+	return op, nil
+}
+
+// parseVariableDefinitions parses optional variable definitions.
+//
+//	VariableDefinitions : ( VariableDefinition+ )
+//	VariableDefinition : Variable : Type DefaultValue? Directives?
+func (p *parser) parseVariableDefinitions() ([]*VariableDefinition, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var vars []*VariableDefinition
+	for {
+		start := p.tok
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			if v.Variable == n {
+				return nil, p.errorAt(start, "duplicate variable $%s", n)
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v := &VariableDefinition{Variable: n}
+		if v.Type, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if v.DefaultValue, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		if v.Directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+		if ok, err := p.skip(")"); err != nil {
+			return nil, err
+		} else if ok {
+			return vars, nil
+		}
+	}
+}
+
+// parseFragmentDefinition parses a fragment definition.
+//
+//	FragmentDefinition : fragment FragmentName TypeCondition Directives? SelectionSet
+func (p *parser) parseFragmentDefinition() (*FragmentDefinition, error) {
+	start := p.tok.pos
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == name && p.tok.value == "on" {
+		return nil, p.errorf("expected fragment name, found %v", p.tok)
+	}
+	n, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != name || p.tok.value != "on" {
+		return nil, p.errorf("expected \"on\", found %v", p.tok)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	f := &FragmentDefinition{Name: n}
+	if f.TypeCondition, err = p.parseName(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if f.SelectionSet, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	f.Source = p.lex.src[start:p.end]
+	return f, nil
+}
+
+// parseSelectionSet parses a selection set.
+//
+//	SelectionSet : { Selection+ }
+//	Selection : Field | FragmentSpread | InlineFragment
+func (p *parser) parseSelectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []Selection
+	for {
+		var sel Selection
+		if p.peek("...") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind == name && p.tok.value != "on" {
+				if p.tag {
+					return nil, p.errorf("expected \"on\" or directive, found %v (named fragment spreads are not supported)", p.tok)
+				}
+				s := &FragmentSpread{Name: p.tok.value}
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				var err error
+				if s.Directives, err = p.parseDirectives(); err != nil {
+					return nil, err
+				}
+				sel = s
+			} else {
+				f, err := p.parseInlineFragmentRest()
+				if err != nil {
+					return nil, err
+				}
+				if f.SelectionSet, err = p.parseSelectionSet(); err != nil {
+					return nil, err
+				}
+				sel = f
+			}
+		} else {
+			f, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek("{") {
+				if f.SelectionSet, err = p.parseSelectionSet(); err != nil {
+					return nil, err
+				}
+			}
+			sel = f
+		}
+		sels = append(sels, sel)
+		if p.peek("}") {
+			p.end = p.lex.pos
+			return sels, p.advance()
+		}
+	}
+}
+
+// parseField parses a field, without a selection set.
+//
+//	Field : Alias? Name Arguments? Directives?
+func (p *parser) parseField() (*Field, error) {
+	f := new(Field)
+	n, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.Alias = n
+		if n, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	f.Name = n
+	if f.Arguments, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseInlineFragment parses an inline fragment, without a selection set.
+//
+//	InlineFragment : ... TypeCondition? Directives?
+func (p *parser) parseInlineFragment() (*InlineFragment, error) {
+	if err := p.expect("..."); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == name && p.tok.value != "on" {
+		return nil, p.errorf("expected \"on\" or directive, found %v (named fragment spreads are not supported)", p.tok)
+	}
+	return p.parseInlineFragmentRest()
+}
+
+// parseInlineFragmentRest parses the rest of an inline fragment after "...",
+// without a selection set.
+func (p *parser) parseInlineFragmentRest() (*InlineFragment, error) {
+	f := new(InlineFragment)
+	if p.tok.kind == name && p.tok.value == "on" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		f.TypeCondition = n
+	}
+	var err error
+	if f.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseArguments parses optional arguments.
+//
+//	Arguments : ( Argument+ )
+func (p *parser) parseArguments() ([]*Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*Argument
+	for {
+		start := p.tok
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range args {
+			if a.Name == n {
+				return nil, p.errorAt(start, "duplicate argument %q", n)
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: n, Value: v})
+		if ok, err := p.skip(")"); err != nil {
+			return nil, err
+		} else if ok {
+			return args, nil
+		}
+	}
+}
+
+// parseDirectives parses optional directives.
+//
+//	Directives : Directive+
+//	Directive : @ Name Arguments?
+func (p *parser) parseDirectives() ([]*Directive, error) {
+	var ds []*Directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, &Directive{Name: n, Arguments: args})
+	}
+	return ds, nil
+}
+
+// parseValue parses an input value.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Input-Values.
+func (p *parser) parseValue() (Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case intValue:
+		return &IntValue{Raw: tok.value}, p.advance()
+	case floatValue:
+		return &FloatValue{Raw: tok.value}, p.advance()
+	case stringValue:
+		return &StringValue{Value: tok.value}, p.advance()
+	case name:
+		var v Value
+		switch tok.value {
+		case "true", "false":
+			v = &BooleanValue{Value: tok.value == "true"}
+		case "null":
+			v = &NullValue{}
+		default:
+			v = &EnumValue{Name: tok.value}
+		}
+		return v, p.advance()
+	}
+	switch {
+	case p.peek("$"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return &Variable{Name: n}, nil
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		l := &ListValue{}
+		for !p.peek("]") {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			l.Values = append(l.Values, v)
+		}
+		return l, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		o := &ObjectValue{}
+		for !p.peek("}") {
+			start := p.tok
+			n, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range o.Fields {
+				if f.Name == n {
+					return nil, p.errorAt(start, "duplicate input object field %q", n)
+				}
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			o.Fields = append(o.Fields, &ObjectField{Name: n, Value: v})
+		}
+		return o, p.advance()
+	default:
+		return nil, p.errorf("expected value, found %v", p.tok)
+	}
+}
+
+// parseType parses a type reference.
+//
+//	Type : NamedType | ListType | NonNullType
+func (p *parser) parseType() (Type, error) {
+	var t Type
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		t = &ListType{Type: elem}
+	} else {
+		n, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		t = &NamedType{Name: n}
+	}
+	if ok, err := p.skip("!"); err != nil {
+		return nil, err
+	} else if ok {
+		t = &NonNullType{Type: t}
+	}
+	return t, nil
+}
+
+// unexpected returns an error about the current token being unexpected.
+func (p *parser) unexpected() error {
+	return p.errorf("unexpected %v", p.tok)
+}
+
+// errorf returns a SyntaxError at the current token.
+func (p *parser) errorf(format string, a ...any) error {
+	return p.errorAt(p.tok, format, a...)
+}
+
+// errorAt returns a SyntaxError at token tok.
+func (p *parser) errorAt(tok token, format string, a ...any) error {
+	return &SyntaxError{Source: p.lex.src, Offset: tok.pos, Msg: fmt.Sprintf(format, a...)}
+}
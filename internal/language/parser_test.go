@@ -0,0 +1,261 @@
+package language_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		in   string
+		want language.Selection
+	}{
+		{
+			in:   "viewer",
+			want: &language.Field{Name: "viewer"},
+		},
+		{
+			in:   " node1 : node(id: \"MDEy\\\"Ok\\u00e9\") ",
+			want: &language.Field{Alias: "node1", Name: "node", Arguments: []*language.Argument{{Name: "id", Value: &language.StringValue{Value: "MDEy\"Oké"}}}},
+		},
+		{
+			in: `comments(first:1after:$cursor)`,
+			want: &language.Field{Name: "comments", Arguments: []*language.Argument{
+				{Name: "first", Value: &language.IntValue{Raw: "1"}},
+				{Name: "after", Value: &language.Variable{Name: "cursor"}},
+			}},
+		},
+		{
+			in: `search(query: """
+				multi
+				  line
+			""", type: ISSUE, weight: -1.5e3, archived: false, owner: null)`,
+			want: &language.Field{Name: "search", Arguments: []*language.Argument{
+				{Name: "query", Value: &language.StringValue{Value: "multi\n  line"}},
+				{Name: "type", Value: &language.EnumValue{Name: "ISSUE"}},
+				{Name: "weight", Value: &language.FloatValue{Raw: "-1.5e3"}},
+				{Name: "archived", Value: &language.BooleanValue{Value: false}},
+				{Name: "owner", Value: &language.NullValue{}},
+			}},
+		},
+		{
+			in: `issues(filterBy: {states: [OPEN, CLOSED], labels: ["bug"], assignee: $login, since: {}}, orderBy: [])`,
+			want: &language.Field{Name: "issues", Arguments: []*language.Argument{
+				{Name: "filterBy", Value: &language.ObjectValue{Fields: []*language.ObjectField{
+					{Name: "states", Value: &language.ListValue{Values: []language.Value{&language.EnumValue{Name: "OPEN"}, &language.EnumValue{Name: "CLOSED"}}}},
+					{Name: "labels", Value: &language.ListValue{Values: []language.Value{&language.StringValue{Value: "bug"}}}},
+					{Name: "assignee", Value: &language.Variable{Name: "login"}},
+					{Name: "since", Value: &language.ObjectValue{}},
+				}}},
+				{Name: "orderBy", Value: &language.ListValue{}},
+			}},
+		},
+		{
+			in: `name @include(if: $withName) @deprecated @custom(a: 1, b: [2])`,
+			want: &language.Field{Name: "name", Directives: []*language.Directive{
+				{Name: "include", Arguments: []*language.Argument{{Name: "if", Value: &language.Variable{Name: "withName"}}}},
+				{Name: "deprecated"},
+				{Name: "custom", Arguments: []*language.Argument{
+					{Name: "a", Value: &language.IntValue{Raw: "1"}},
+					{Name: "b", Value: &language.ListValue{Values: []language.Value{&language.IntValue{Raw: "2"}}}},
+				}},
+			}},
+		},
+		{
+			in:   "... on User",
+			want: &language.InlineFragment{TypeCondition: "User"},
+		},
+		{
+			in:   "...on Droid @skip(if: true)",
+			want: &language.InlineFragment{TypeCondition: "Droid", Directives: []*language.Directive{{Name: "skip", Arguments: []*language.Argument{{Name: "if", Value: &language.BooleanValue{Value: true}}}}}},
+		},
+		{
+			in:   "... @include(if: $x)",
+			want: &language.InlineFragment{Directives: []*language.Directive{{Name: "include", Arguments: []*language.Argument{{Name: "if", Value: &language.Variable{Name: "x"}}}}}},
+		},
+		{
+			in: "viewer { login, status { emoji } ... on User { bio } }",
+			want: &language.Field{Name: "viewer", SelectionSet: []language.Selection{
+				&language.Field{Name: "login"},
+				&language.Field{Name: "status", SelectionSet: []language.Selection{&language.Field{Name: "emoji"}}},
+				&language.InlineFragment{TypeCondition: "User", SelectionSet: []language.Selection{&language.Field{Name: "bio"}}},
+			}},
+		},
+		{
+			in:   "\n\tviewer,\n",
+			want: &language.Field{Name: "viewer"},
+		},
+	}
+	for _, tc := range tests {
+		got, err := language.ParseTag(tc.in)
+		if err != nil {
+			t.Errorf("ParseTag(%q): %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseTag(%q):\n got: %#v\nwant: %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseTag_error(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `syntax error at column 1: expected name, found end of input`},
+		{"viewer{}", `syntax error at column 8: expected name, found "}"`},
+		{"viewer{...UserFields}", `syntax error at column 11: expected "on" or directive, found "UserFields" (named fragment spreads are not supported)`},
+		{"... on User{login}", `syntax error at column 12: unexpected "{"`},
+		{"a: b: c", `syntax error at column 5: unexpected ":"`},
+		{"issue(number: )", `syntax error at column 15: expected value, found ")"`},
+		{"issue(number: 1", `syntax error at column 16: expected name, found end of input`},
+		{"issue()", `syntax error at column 7: expected name, found ")"`},
+		{"issue(a: 1, a: 2)", `syntax error at column 13: duplicate argument "a"`},
+		{"issue(a: {b: 1, b: 2})", `syntax error at column 17: duplicate input object field "b"`},
+		{"issue(a: 01)", `syntax error at column 11: invalid number, unexpected digit after 0`},
+		{"issue(a: 1.)", `syntax error at column 12: invalid number, expected digit after "."`},
+		{`issue(a: "abc)`, `syntax error at column 10: unterminated string`},
+		{`issue(a: "\x")`, `syntax error at column 11: invalid escape sequence "\\x"`},
+		{"issue(a: [1, 2)", `syntax error at column 15: expected value, found ")"`},
+		{"... User", `syntax error at column 5: expected "on" or directive, found "User" (named fragment spreads are not supported)`},
+		{"... on", `syntax error at column 7: expected name, found end of input`},
+		{".. on User", `syntax error at column 1: unexpected '.', did you mean "..."?`},
+		{"name @", `syntax error at column 7: expected name, found end of input`},
+		{"name\n%", `syntax error at line 2, column 1: unexpected character '%'`},
+		{"login # The user's login.", `syntax error at column 7: comments aren't allowed in graphql struct tags`},
+		{`search(query: "#1") # Comment.`, `syntax error at column 21: comments aren't allowed in graphql struct tags`},
+	}
+	for _, tc := range tests {
+		_, err := language.ParseTag(tc.in)
+		if err == nil {
+			t.Errorf("ParseTag(%q): got error: nil, want: %v", tc.in, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("ParseTag(%q):\n got error: %v\nwant error: %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    language.Type
+		wantErr string
+	}{
+		{in: "String", want: &language.NamedType{Name: "String"}},
+		{in: " [ID!] ! ", want: &language.NonNullType{Type: &language.ListType{Type: &language.NonNullType{Type: &language.NamedType{Name: "ID"}}}}},
+		{in: "[[Int]]", want: &language.ListType{Type: &language.ListType{Type: &language.NamedType{Name: "Int"}}}},
+		{in: "String!!", wantErr: `syntax error at column 8: unexpected "!"`},
+		{in: "[String", wantErr: `syntax error at column 8: expected "]", found end of input`},
+		{in: "", wantErr: `syntax error at column 1: expected name, found end of input`},
+	}
+	for _, tc := range tests {
+		got, err := language.ParseType(tc.in)
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("ParseType(%q): got error: %v, want: %v", tc.in, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseType(%q): %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseType(%q):\n got: %#v\nwant: %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	const src = `# Queries for heroes.
+query Hero($episode: Episode = JEDI, $withFriends: Boolean!) @cached {
+	hero(episode: $episode) {
+		...CharacterFields
+		friends @include(if: $withFriends) { name }
+		... on Droid { primaryFunction }
+	}
+}
+
+fragment CharacterFields on Character { name }
+
+{ viewer { login } }
+`
+	got, err := language.ParseDocument(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &language.Document{Definitions: []language.Definition{
+		&language.OperationDefinition{
+			Operation: "query",
+			Name:      "Hero",
+			VariableDefinitions: []*language.VariableDefinition{
+				{Variable: "episode", Type: &language.NamedType{Name: "Episode"}, DefaultValue: &language.EnumValue{Name: "JEDI"}},
+				{Variable: "withFriends", Type: &language.NonNullType{Type: &language.NamedType{Name: "Boolean"}}},
+			},
+			Directives: []*language.Directive{{Name: "cached"}},
+			SelectionSet: []language.Selection{
+				&language.Field{
+					Name:      "hero",
+					Arguments: []*language.Argument{{Name: "episode", Value: &language.Variable{Name: "episode"}}},
+					SelectionSet: []language.Selection{
+						&language.FragmentSpread{Name: "CharacterFields"},
+						&language.Field{
+							Name:         "friends",
+							Directives:   []*language.Directive{{Name: "include", Arguments: []*language.Argument{{Name: "if", Value: &language.Variable{Name: "withFriends"}}}}},
+							SelectionSet: []language.Selection{&language.Field{Name: "name"}},
+						},
+						&language.InlineFragment{TypeCondition: "Droid", SelectionSet: []language.Selection{&language.Field{Name: "primaryFunction"}}},
+					},
+				},
+			},
+			Source: src[len("# Queries for heroes.\n"):strings.Index(src, "\n\nfragment")],
+		},
+		&language.FragmentDefinition{
+			Name:          "CharacterFields",
+			TypeCondition: "Character",
+			SelectionSet:  []language.Selection{&language.Field{Name: "name"}},
+			Source:        "fragment CharacterFields on Character { name }",
+		},
+		&language.OperationDefinition{
+			Operation: "query",
+			SelectionSet: []language.Selection{
+				&language.Field{Name: "viewer", SelectionSet: []language.Selection{&language.Field{Name: "login"}}},
+			},
+			Source: "{ viewer { login } }",
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDocument:\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestParseDocument_error(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `syntax error at column 1: expected operation or fragment definition, found end of input`},
+		{"query Q { }", `syntax error at column 11: expected name, found "}"`},
+		{"query Q", `syntax error at column 8: expected "{", found end of input`},
+		{"query Q($a: Int, $a: Int) { a }", `syntax error at column 18: duplicate variable $a`},
+		{"fragment on User { a }", `syntax error at column 10: expected fragment name, found "on"`},
+		{"fragment F User { a }", `syntax error at column 12: expected "on", found "User"`},
+		{"type Query { a: Int }", `syntax error at column 1: expected operation or fragment definition, found "type"`},
+	}
+	for _, tc := range tests {
+		_, err := language.ParseDocument(tc.in)
+		if err == nil {
+			t.Errorf("ParseDocument(%q): got error: nil, want: %v", tc.in, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("ParseDocument(%q):\n got error: %v\nwant error: %v", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package language
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// WriteSelection writes sel, without its selection set, to w.
+// If pretty is true, insignificant spaces and commas are included
+// for readability, otherwise the output is minified.
+//
+// E.g., "alias:name(a:1,b:[2,3])@include(if:$x)" when minified,
+// or "alias: name(a: 1, b: [2, 3]) @include(if: $x)" when pretty.
+func WriteSelection(w io.Writer, sel Selection, pretty bool) {
+	p := printer{w: w, pretty: pretty}
+	switch sel := sel.(type) {
+	case *Field:
+		if sel.Alias != "" {
+			p.str(sel.Alias)
+			p.sep(":", ": ")
+		}
+		p.str(sel.Name)
+		p.arguments(sel.Arguments)
+		p.directives(sel.Directives)
+	case *InlineFragment:
+		p.str("...")
+		if sel.TypeCondition != "" {
+			p.str(" on ")
+			p.str(sel.TypeCondition)
+		}
+		p.directives(sel.Directives)
+	case *FragmentSpread:
+		p.str("...")
+		p.str(sel.Name)
+		p.directives(sel.Directives)
+	default:
+		panic(fmt.Errorf("unexpected selection type %T", sel))
+	}
+}
+
+// WriteValue writes v to w. See WriteSelection for the meaning of pretty.
+func WriteValue(w io.Writer, v Value, pretty bool) {
+	p := printer{w: w, pretty: pretty}
+	p.value(v)
+}
+
+// WriteType writes type reference t to w.
+func WriteType(w io.Writer, t Type) {
+	switch t := t.(type) {
+	case *NamedType:
+		io.WriteString(w, t.Name)
+	case *ListType:
+		io.WriteString(w, "[")
+		WriteType(w, t.Type)
+		io.WriteString(w, "]")
+	case *NonNullType:
+		WriteType(w, t.Type)
+		io.WriteString(w, "!")
+	default:
+		panic(fmt.Errorf("unexpected type %T", t))
+	}
+}
+
+type printer struct {
+	w      io.Writer
+	pretty bool
+}
+
+func (p printer) str(s string) { io.WriteString(p.w, s) }
+
+// sep writes a separator, the minified or pretty one.
+func (p printer) sep(minified, pretty string) {
+	if p.pretty {
+		p.str(pretty)
+	} else {
+		p.str(minified)
+	}
+}
+
+func (p printer) arguments(args []*Argument) {
+	if len(args) == 0 {
+		return
+	}
+	p.str("(")
+	for i, a := range args {
+		if i != 0 {
+			p.sep(",", ", ")
+		}
+		p.str(a.Name)
+		p.sep(":", ": ")
+		p.value(a.Value)
+	}
+	p.str(")")
+}
+
+func (p printer) directives(ds []*Directive) {
+	for _, d := range ds {
+		p.sep("", " ")
+		p.str("@")
+		p.str(d.Name)
+		p.arguments(d.Arguments)
+	}
+}
+
+func (p printer) value(v Value) {
+	switch v := v.(type) {
+	case *Variable:
+		p.str("$")
+		p.str(v.Name)
+	case *IntValue:
+		p.str(v.Raw)
+	case *FloatValue:
+		p.str(v.Raw)
+	case *StringValue:
+		p.str(Quote(v.Value))
+	case *BooleanValue:
+		if v.Value {
+			p.str("true")
+		} else {
+			p.str("false")
+		}
+	case *NullValue:
+		p.str("null")
+	case *EnumValue:
+		p.str(v.Name)
+	case *ListValue:
+		p.str("[")
+		for i, v := range v.Values {
+			if i != 0 {
+				p.sep(",", ", ")
+			}
+			p.value(v)
+		}
+		p.str("]")
+	case *ObjectValue:
+		p.str("{")
+		for i, f := range v.Fields {
+			if i != 0 {
+				p.sep(",", ", ")
+			}
+			p.str(f.Name)
+			p.sep(":", ": ")
+			p.value(f.Value)
+		}
+		p.str("}")
+	default:
+		panic(fmt.Errorf("unexpected value type %T", v))
+	}
+}
+
+// Quote returns a GraphQL string value representing s.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-String-Value.
+func Quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\b':
+			sb.WriteString(`\b`)
+		case r == '\f':
+			sb.WriteString(`\f`)
+		case r < 0x20 || r == 0x7f || r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// IsName reports whether s is a valid GraphQL name.
+//
+// Specification: https://spec.graphql.org/October2021/#Name.
+func IsName(s string) bool {
+	if s == "" || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameContinue(s[i]) {
+			return false
+		}
+	}
+	return true
+}
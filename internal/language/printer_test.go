@@ -0,0 +1,59 @@
+package language_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+func TestWriteSelection(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantMinified string
+		wantPretty   string
+	}{
+		{
+			in:           `alias : name ( a : 1 , b : [ 2 , 3 ] , c : { d : "e" , f : $g } ) @include ( if : $x ) @deprecated`,
+			wantMinified: `alias:name(a:1,b:[2,3],c:{d:"e",f:$g})@include(if:$x)@deprecated`,
+			wantPretty:   `alias: name(a: 1, b: [2, 3], c: {d: "e", f: $g}) @include(if: $x) @deprecated`,
+		},
+		{
+			in:           `...on User@skip(if:false)`,
+			wantMinified: `... on User@skip(if:false)`,
+			wantPretty:   `... on User @skip(if: false)`,
+		},
+		{
+			in:           `search(query: "a\"b\\c\n\u0001é", type: ISSUE, x: null, y: -1.5e3)`,
+			wantMinified: `search(query:"a\"b\\c\n\u0001é",type:ISSUE,x:null,y:-1.5e3)`,
+			wantPretty:   `search(query: "a\"b\\c\n\u0001é", type: ISSUE, x: null, y: -1.5e3)`,
+		},
+	}
+	for _, tc := range tests {
+		sel, err := language.ParseTag(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf strings.Builder
+		language.WriteSelection(&buf, sel, false)
+		if got := buf.String(); got != tc.wantMinified {
+			t.Errorf("minified:\n got: %s\nwant: %s", got, tc.wantMinified)
+		}
+		buf.Reset()
+		language.WriteSelection(&buf, sel, true)
+		if got := buf.String(); got != tc.wantPretty {
+			t.Errorf("pretty:\n got: %s\nwant: %s", got, tc.wantPretty)
+		}
+
+		// The output should parse back into the same selection.
+		sel2, err := language.ParseTag(tc.wantMinified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		language.WriteSelection(&buf, sel2, true)
+		if got := buf.String(); got != tc.wantPretty {
+			t.Errorf("round trip:\n got: %s\nwant: %s", got, tc.wantPretty)
+		}
+	}
+}
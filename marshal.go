@@ -0,0 +1,272 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/shurcooL/graphql/internal/fields"
+)
+
+// Unmarshal decodes data, the JSON-encoded data of a GraphQL response,
+// into v the same way as Client.Query does. It's useful for responses
+// that are cached, recorded in test fixtures, or received by other means.
+// See Client.Exec for what v can be.
+//
+// Options that affect how responses are decoded, such as LenientDecoding,
+// are applied. Other options have no effect.
+func Unmarshal(data []byte, v any, opts ...Option) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := decodeData(dec, v, newOptions(opts))
+	if err != nil {
+		return err
+	}
+	tok, err := dec.Token()
+	switch err {
+	case io.EOF:
+		return nil
+	case nil:
+		return fmt.Errorf("invalid token '%v' after top-level value", tok)
+	default:
+		return err
+	}
+}
+
+// Marshal returns the JSON encoding of v, a GraphQL query data structure
+// such as one populated by Client.Query, in the shape of the response data
+// it was populated from. Object keys are GraphQL response keys, i.e., field
+// names or aliases set in graphql struct tags, and the fields of inline
+// fragments, named fragments and embedded structs are flattened into their
+// enclosing object. The result can be decoded into v again with Unmarshal.
+//
+// Objects include __typename when it's known: from a struct field with
+// `graphql:"__typename"` tag, or from the possible type registered for the
+// dynamic type of an interface value. When __typename is known, fragments
+// that Unmarshal would skip for it are left out. Marshal returns an error
+// for an object that has more than one fragment with a type condition,
+// but no known __typename, since Unmarshal would populate all of them.
+//
+// Values of custom scalars registered with CustomScalar in opts are encoded
+// by them. Other options have no effect. Other scalars, and values that are
+// decoded from entire JSON values, such as maps and json.RawMessage, are
+// encoded by encoding/json. Custom collections that implement Appender are
+// encoded as lists if they implement Ranger, and are an error otherwise.
+func Marshal(v any, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	err := encodeValue(&buf, reflect.ValueOf(v), newOptions(opts).scalars)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue writes the JSON encoding of v to buf. Values of custom
+// scalars in scalars are encoded by them.
+func encodeValue(buf *bytes.Buffer, v reflect.Value, scalars scalars) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	t := v.Type()
+	if s, ok := scalars[t]; ok {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		j, err := s.encode(v)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(j)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		// Follow pointers before anything else,
+		// since they may point to custom scalars.
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeValue(buf, v.Elem(), scalars)
+	}
+	if fields.IsAppender(t) {
+		return encodeCollection(buf, v, scalars)
+	}
+	if t.Implements(jsonMarshaler) || reflect.PtrTo(t).Implements(jsonMarshaler) || fields.CapturesValue(t) {
+		return encodeJSON(buf, v)
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		for _, pt := range fields.PossibleTypes(t) {
+			if pt.Type == v.Elem().Type() {
+				return encodeObject(buf, reflect.Indirect(v.Elem()), pt.Name, scalars)
+			}
+		}
+		return encodeValue(buf, v.Elem(), scalars)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			err := encodeValue(buf, v.Index(i), scalars)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Struct:
+		return encodeObject(buf, v, "", scalars)
+	default:
+		return encodeJSON(buf, v)
+	}
+}
+
+// encodeCollection writes the JSON encoding of v, a custom collection
+// whose pointer type implements Appender, to buf, as a list.
+func encodeCollection(buf *bytes.Buffer, v reflect.Value, scalars scalars) error {
+	if !v.CanAddr() {
+		// RangeGraphQL may have a pointer receiver.
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	r, ok := v.Addr().Interface().(Ranger)
+	if !ok {
+		return fmt.Errorf("collection type %v implements Appender, but not Ranger, so it can't be encoded as a list", v.Type())
+	}
+	var err error
+	n := 0
+	buf.WriteByte('[')
+	r.RangeGraphQL(func(elem any) bool {
+		if n != 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		err = encodeValue(buf, reflect.ValueOf(elem), scalars)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// encodeObject writes the JSON encoding of struct v to buf, as an object.
+// If name isn't empty, it's written as the value of __typename first.
+func encodeObject(buf *bytes.Buffer, v reflect.Value, name string, scalars scalars) error {
+	written := make(map[string]bool)
+	buf.WriteByte('{')
+	if name != "" {
+		written["__typename"] = true
+		buf.WriteString(`"__typename":`)
+		encodeString(buf, name)
+	} else {
+		name = typename(v)
+	}
+	err := encodeFields(buf, v, name, written, scalars)
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// encodeFields writes the fields of struct v as JSON object members to buf,
+// flattening inline fragments and embedded structs. Inline fragments that
+// don't match typename are skipped, following the rules of decoding, as are
+// fields whose response key is in written, because it's already written.
+func encodeFields(buf *bytes.Buffer, v reflect.Value, typename string, written map[string]bool, scalars scalars) error {
+	fs, err := fields.Of(v.Type())
+	if err != nil {
+		return err
+	}
+	// Like when decoding, fragments are skipped only if
+	// a sibling fragment matches typename exactly.
+	matched := false
+	var typeConditions []string
+	for _, f := range fs {
+		if f.TypeCondition != "" && f.TypeCondition == typename {
+			matched = true
+		}
+		if f.TypeCondition != "" {
+			typeConditions = append(typeConditions, f.TypeCondition)
+		}
+	}
+	if typename == "" && len(typeConditions) > 1 {
+		return fmt.Errorf("fragments on %s can't be told apart without __typename, add a struct field with `graphql:\"__typename\"` tag to their selection set", strings.Join(typeConditions, ", "))
+	}
+	for _, f := range fs {
+		fv := v.Field(f.Index)
+		if f.Inline || f.Fragment {
+			if matched && f.TypeCondition != "" && f.TypeCondition != typename {
+				continue
+			}
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() != reflect.Struct {
+				continue
+			}
+			err := encodeFields(buf, fv, typename, written, scalars)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Exported || !fv.CanInterface() || written[f.Name] {
+			continue
+		}
+		if len(written) > 0 {
+			buf.WriteByte(',')
+		}
+		written[f.Name] = true
+		encodeString(buf, f.Name)
+		buf.WriteByte(':')
+		err := encodeValue(buf, fv, scalars)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJSON writes the JSON encoding of v by encoding/json to buf.
+func encodeJSON(buf *bytes.Buffer, v reflect.Value) error {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		// Use the pointer, in case only the pointer type implements json.Marshaler.
+		v = v.Addr()
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// encodeString writes the JSON encoding of string s to buf.
+func encodeString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
@@ -0,0 +1,51 @@
+package graphql
+
+import (
+	"sort"
+	"sync"
+)
+
+// Nulls is a set of response paths, such as "repository.owner" or
+// "search.nodes[3].author", of nulls that were decoded into structs that
+// aren't behind a pointer. See RecordNulls. The zero Nulls is an empty set,
+// ready to use. It's safe for concurrent use.
+type Nulls struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+// add adds response path to n.
+func (n *Nulls) add(path string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.paths == nil {
+		n.paths = make(map[string]bool)
+	}
+	n.paths[path] = true
+}
+
+// Has reports whether the value at response path was null.
+func (n *Nulls) Has(path string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.paths[path]
+}
+
+// Paths returns the response paths in n, sorted.
+func (n *Nulls) Paths() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	paths := make([]string, 0, len(n.paths))
+	for path := range n.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Reset removes all response paths from n, so it can be reused.
+func (n *Nulls) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.paths = nil
+}
@@ -0,0 +1,427 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/shurcooL/graphql/internal/fields"
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// Operations is a set of named GraphQL operations, parsed from documents
+// such as .graphql files. Use ParseFS to create one.
+type Operations struct {
+	operations map[string]*Operation
+}
+
+// Operation is a named GraphQL operation, along with
+// the fragment definitions that it uses.
+type Operation struct {
+	name      string
+	document  string // Source text of the operation and the fragment definitions it uses.
+	def       *language.OperationDefinition
+	fragments map[string]*language.FragmentDefinition // Fragment definitions of all documents, by name.
+}
+
+// ParseFS parses the GraphQL documents in fsys that match patterns,
+// as defined by fs.Glob, and returns their operations. It's meant
+// to be used with an embed.FS, e.g.:
+//
+//	//go:embed queries/*.graphql
+//	var queries embed.FS
+//
+//	ops, err := graphql.ParseFS(queries, "queries/*.graphql")
+//
+// Operations must be named, and names of operations and fragments must be
+// unique across all documents. Fragments defined in one document can be
+// used in another, but their spreads must not form a cycle.
+func ParseFS(fsys fs.FS, patterns ...string) (*Operations, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("pattern %q matches no files", pattern)
+		}
+		files = append(files, matches...)
+	}
+
+	ops := &Operations{operations: make(map[string]*Operation)}
+	fragments := make(map[string]*language.FragmentDefinition)
+	defined := make(map[string]string) // File where each operation or fragment is defined, by kind and name.
+	for _, file := range files {
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := language.ParseDocument(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		for _, def := range doc.Definitions {
+			var key string
+			switch def := def.(type) {
+			case *language.OperationDefinition:
+				if def.Name == "" {
+					return nil, fmt.Errorf("%s: anonymous %s can't be looked up, it must have a name", file, def.Operation)
+				}
+				key = "operation " + def.Name
+				ops.operations[def.Name] = &Operation{name: def.Name, def: def, fragments: fragments}
+			case *language.FragmentDefinition:
+				key = "fragment " + def.Name
+				fragments[def.Name] = def
+			}
+			if prev, ok := defined[key]; ok {
+				return nil, fmt.Errorf("%s: %s is already defined in %s", file, key, prev)
+			}
+			defined[key] = file
+		}
+	}
+
+	err := checkFragmentCycles(fragments, defined)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range ops.Names() {
+		op := ops.operations[name]
+		var names []string // Names of fragments used by op, in order of first use.
+		err := op.usedFragments(op.def.SelectionSet, make(map[string]bool), &names)
+		if err != nil {
+			return nil, fmt.Errorf("%s: operation %s: %v", defined["operation "+op.name], op.name, err)
+		}
+		sources := []string{op.def.Source}
+		for _, name := range names {
+			sources = append(sources, fragments[name].Source)
+		}
+		op.document = strings.Join(sources, "\n\n")
+	}
+	return ops, nil
+}
+
+// checkFragmentCycles checks that the spreads of fragments don't form a cycle,
+// such as "fragment A on User{...B} fragment B on User{...A}". defined maps
+// fragments to the files they're defined in, as in ParseFS.
+func checkFragmentCycles(fragments map[string]*language.FragmentDefinition, defined map[string]string) error {
+	names := make([]string, 0, len(fragments))
+	for name := range fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	done := make(map[string]bool) // Fragments checked already.
+	for _, name := range names {
+		cycle := fragmentCycle(fragments, name, nil, done)
+		if cycle != nil {
+			return fmt.Errorf("%s: fragment %s: fragment spreads form a cycle: %s", defined["fragment "+cycle[0]], cycle[0], strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// fragmentCycle returns a cycle of fragment spreads that the fragment
+// with the given name is in, or reaches, such as ["A", "B", "A"], or nil
+// if there's none. stack holds the fragments that spread into it, and done
+// the ones known not to reach a cycle.
+func fragmentCycle(fragments map[string]*language.FragmentDefinition, name string, stack []string, done map[string]bool) []string {
+	for i, s := range stack {
+		if s == name {
+			return append(stack[i:len(stack):len(stack)], name)
+		}
+	}
+	f, ok := fragments[name]
+	if done[name] || !ok {
+		// Undefined fragments are reported by usedFragments.
+		return nil
+	}
+	stack = append(stack, name)
+	for _, spread := range fragmentSpreads(f.SelectionSet, nil) {
+		if cycle := fragmentCycle(fragments, spread, stack, done); cycle != nil {
+			return cycle
+		}
+	}
+	done[name] = true
+	return nil
+}
+
+// fragmentSpreads appends to names the names of fragments spread in sels,
+// not including the fragments that those spread in turn.
+func fragmentSpreads(sels []language.Selection, names []string) []string {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *language.Field:
+			names = fragmentSpreads(sel.SelectionSet, names)
+		case *language.InlineFragment:
+			names = fragmentSpreads(sel.SelectionSet, names)
+		case *language.FragmentSpread:
+			names = append(names, sel.Name)
+		}
+	}
+	return names
+}
+
+// usedFragments appends to names the names of fragments that sels use,
+// directly or indirectly, skipping the ones in seen.
+func (op *Operation) usedFragments(sels []language.Selection, seen map[string]bool, names *[]string) error {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *language.Field:
+			err := op.usedFragments(sel.SelectionSet, seen, names)
+			if err != nil {
+				return err
+			}
+		case *language.InlineFragment:
+			err := op.usedFragments(sel.SelectionSet, seen, names)
+			if err != nil {
+				return err
+			}
+		case *language.FragmentSpread:
+			if seen[sel.Name] {
+				continue
+			}
+			f, ok := op.fragments[sel.Name]
+			if !ok {
+				return fmt.Errorf("fragment %s is used, but not defined", sel.Name)
+			}
+			seen[sel.Name] = true
+			*names = append(*names, sel.Name)
+			err := op.usedFragments(f.SelectionSet, seen, names)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the operation with the given name, or nil if there's none.
+func (ops *Operations) Lookup(name string) *Operation {
+	return ops.operations[name]
+}
+
+// Names returns the names of the operations, sorted.
+func (ops *Operations) Names() []string {
+	names := make([]string, 0, len(ops.operations))
+	for name := range ops.operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the name of the operation.
+func (op *Operation) Name() string { return op.name }
+
+// Document returns the GraphQL document that's sent to execute the operation.
+// It contains the operation and the fragment definitions that it uses.
+func (op *Operation) Document() string { return op.document }
+
+// ExecOperation executes the GraphQL operation op, populating the response
+// data into v. See Exec for what v can be.
+//
+// It returns an error without executing op if a variable it requires
+// isn't provided, or if one it doesn't define is.
+func (c *Client) ExecOperation(ctx context.Context, op *Operation, v any, variables map[string]any, opts ...Option) error {
+	if op == nil {
+		return fmt.Errorf("operation is nil")
+	}
+	err := op.checkVariables(variables)
+	if err != nil {
+		return err
+	}
+	o := c.options(opts)
+	o.operationName = op.name
+	return c.exec(ctx, op.document, v, variables, o)
+}
+
+// checkVariables checks that variables are the ones defined by op.
+func (op *Operation) checkVariables(variables map[string]any) error {
+	defined := make(map[string]bool, len(op.def.VariableDefinitions))
+	for _, d := range op.def.VariableDefinitions {
+		defined[d.Variable] = true
+		_, required := d.Type.(*language.NonNullType)
+		if _, ok := variables[d.Variable]; !ok && required && d.DefaultValue == nil {
+			return fmt.Errorf("variable $%s is required by operation %s, but not provided", d.Variable, op.name)
+		}
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !defined[name] {
+			return fmt.Errorf("variable $%s isn't defined by operation %s", name, op.name)
+		}
+	}
+	return nil
+}
+
+// Check checks that the shape of v, a struct or pointer to struct, matches
+// the selection set of the operation, so that the response can be decoded
+// into v. It's meant to be used in tests.
+//
+// Every field selected by the operation must have a corresponding struct
+// field, and the other way around, and fields must have a selection set
+// exactly when their struct field has a struct type. Fragments are flattened
+// on both sides, so their type conditions aren't checked. __typename may be
+// selected without a corresponding struct field.
+func (op *Operation) Check(v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil || fields.Indirect(t).Kind() != reflect.Struct {
+		return fmt.Errorf("cannot check %T against operation %s, it must be a struct or pointer to struct", v, op.name)
+	}
+	err := op.checkSelectionSet(fields.Indirect(t), op.def.SelectionSet, "", "")
+	if err != nil {
+		return fmt.Errorf("operation %s: %v", op.name, err)
+	}
+	return nil
+}
+
+// selectedField is a field selected in a selection set, after flattening
+// fragments. Selection sets of fields with the same response key are merged.
+type selectedField struct {
+	key          string
+	selectionSet []language.Selection
+	leaf         bool // Whether the field has no selection set.
+}
+
+// structField is a struct field with a GraphQL name, after
+// flattening embedded structs and inline fragments.
+type structField struct {
+	field fields.Field
+	path  string // Go field path.
+	t     reflect.Type
+}
+
+// checkSelectionSet checks struct type t against the selection set sels.
+// path and responsePath are the Go field path and response path of t.
+func (op *Operation) checkSelectionSet(t reflect.Type, sels []language.Selection, path, responsePath string) error {
+	var selected []*selectedField
+	op.selectedFields(sels, make(map[string]*selectedField), make(map[string]bool), &selected)
+	var sfs []structField
+	err := appendStructFields(&sfs, t, path)
+	if err != nil {
+		return err
+	}
+	for _, sf := range sfs {
+		var sel *selectedField
+		for _, s := range selected {
+			if sf.field.HasName(s.key) {
+				sel = s
+				break
+			}
+		}
+		if sel == nil {
+			return fieldError(sf.path, "field %q isn't selected", join(responsePath, sf.field.Name))
+		}
+		err := op.checkField(sf, sel, join(responsePath, sel.key))
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range selected {
+		if s.key == "__typename" {
+			continue
+		}
+		found := false
+		for _, sf := range sfs {
+			if sf.field.HasName(s.key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("selected field %q has no corresponding struct field", join(responsePath, s.key))
+		}
+	}
+	return nil
+}
+
+// checkField checks struct field sf against selected field sel at responsePath.
+func (op *Operation) checkField(sf structField, sel *selectedField, responsePath string) error {
+	t, err := fields.ListElem(sf.t)
+	if err != nil {
+		return fieldError(sf.path, "%v", err)
+	}
+	object := t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(jsonUnmarshaler) ||
+		t.Kind() == reflect.Interface && fields.PossibleTypes(t) != nil
+	switch {
+	case object && sel.leaf:
+		return fieldError(sf.path, "field %q has no selection set, but Go type %v needs one", responsePath, sf.t)
+	case !object && !sel.leaf && t.Kind() != reflect.Interface && !fields.CapturesValue(t):
+		return fieldError(sf.path, "field %q has a selection set, but Go type %v is a scalar", responsePath, sf.t)
+	case t.Kind() == reflect.Struct && object:
+		return op.checkSelectionSet(t, sel.selectionSet, sf.path, responsePath)
+	}
+	return nil
+}
+
+// selectedFields appends the fields selected by sels to selected,
+// merging the selection sets of fields with the same response key.
+// Fragments in spread are spread already, so they're skipped.
+func (op *Operation) selectedFields(sels []language.Selection, byKey map[string]*selectedField, spread map[string]bool, selected *[]*selectedField) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *language.Field:
+			key := sel.ResponseKey()
+			s, ok := byKey[key]
+			if !ok {
+				s = &selectedField{key: key, leaf: true}
+				byKey[key] = s
+				*selected = append(*selected, s)
+			}
+			if sel.SelectionSet != nil {
+				s.leaf = false
+				s.selectionSet = append(s.selectionSet, sel.SelectionSet...)
+			}
+		case *language.InlineFragment:
+			op.selectedFields(sel.SelectionSet, byKey, spread, selected)
+		case *language.FragmentSpread:
+			f, ok := op.fragments[sel.Name]
+			if !ok || spread[sel.Name] {
+				continue
+			}
+			spread[sel.Name] = true
+			op.selectedFields(f.SelectionSet, byKey, spread, selected)
+		}
+	}
+}
+
+// appendStructFields appends the fields of struct type t to sfs,
+// flattening embedded structs and inline fragments.
+func appendStructFields(sfs *[]structField, t reflect.Type, path string) error {
+	fs, err := fields.Of(t)
+	if err != nil {
+		return err
+	}
+	for _, f := range fs {
+		sf := t.Field(f.Index)
+		fieldPath := join(path, sf.Name)
+		if f.Inline || f.Fragment {
+			if fields.Indirect(sf.Type).Kind() != reflect.Struct {
+				return fieldError(fieldPath, "embedded type %v isn't a struct", sf.Type)
+			}
+			err := appendStructFields(sfs, fields.Indirect(sf.Type), fieldPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		*sfs = append(*sfs, structField{field: f, path: fieldPath, t: sf.Type})
+	}
+	return nil
+}
+
+// join joins path elements a and b with a dot.
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "." + b
+}
@@ -0,0 +1,271 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/shurcooL/graphql"
+)
+
+var testOperations = fstest.MapFS{
+	"queries/hero.graphql": {Data: []byte(`# Hero of an episode.
+query Hero($episode: Episode = JEDI, $withFriends: Boolean!) {
+	hero(episode: $episode) {
+		__typename
+		...CharacterFields
+		friends @include(if: $withFriends) {
+			...CharacterFields
+		}
+		... on Droid {
+			primaryFunction
+		}
+	}
+}
+`)},
+	"queries/viewer.graphql": {Data: []byte(`query Viewer { viewer { login } }`)},
+	"queries/fragments.graphql": {Data: []byte(`fragment CharacterFields on Character {
+	id
+	name
+}
+
+fragment Unused on Character { id }
+`)},
+}
+
+func TestParseFS(t *testing.T) {
+	ops, err := graphql.ParseFS(testOperations, "queries/*.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ops.Names(), []string{"Hero", "Viewer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got Names: %q, want: %q", got, want)
+	}
+	if ops.Lookup("Unused") != nil {
+		t.Error("got non-nil Lookup for fragment Unused")
+	}
+	op := ops.Lookup("Hero")
+	if got, want := op.Name(), "Hero"; got != want {
+		t.Errorf("got Name: %q, want: %q", got, want)
+	}
+	want := `query Hero($episode: Episode = JEDI, $withFriends: Boolean!) {
+	hero(episode: $episode) {
+		__typename
+		...CharacterFields
+		friends @include(if: $withFriends) {
+			...CharacterFields
+		}
+		... on Droid {
+			primaryFunction
+		}
+	}
+}
+
+fragment CharacterFields on Character {
+	id
+	name
+}`
+	if got := op.Document(); got != want {
+		t.Errorf("got Document:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseFS_error(t *testing.T) {
+	tests := []struct {
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			fsys: fstest.MapFS{},
+			want: `pattern "*.graphql" matches no files`,
+		},
+		{
+			fsys: fstest.MapFS{"a.graphql": {Data: []byte(`query A { a(`)}},
+			want: `a.graphql: syntax error at column 13: expected name, found end of input`,
+		},
+		{
+			fsys: fstest.MapFS{"a.graphql": {Data: []byte(`{ a }`)}},
+			want: `a.graphql: anonymous query can't be looked up, it must have a name`,
+		},
+		{
+			fsys: fstest.MapFS{
+				"a.graphql": {Data: []byte(`query A { a }`)},
+				"b.graphql": {Data: []byte(`mutation A { b }`)},
+			},
+			want: `b.graphql: operation A is already defined in a.graphql`,
+		},
+		{
+			fsys: fstest.MapFS{"a.graphql": {Data: []byte(`query A { a { ...F } } fragment F on A { ...G }`)}},
+			want: `a.graphql: operation A: fragment G is used, but not defined`,
+		},
+		{
+			fsys: fstest.MapFS{
+				"a.graphql": {Data: []byte(`query A { viewer { ...A } }`)},
+				"b.graphql": {Data: []byte(`fragment A on User { login ...B } fragment B on User { name ...A }`)},
+			},
+			want: `b.graphql: fragment A: fragment spreads form a cycle: A -> B -> A`,
+		},
+		{
+			fsys: fstest.MapFS{"a.graphql": {Data: []byte(`query A { viewer { login } } fragment F on User { ...G } fragment G on User { friends { ... on User { ...G } } }`)}},
+			want: `a.graphql: fragment G: fragment spreads form a cycle: G -> G`,
+		},
+	}
+	for _, tc := range tests {
+		_, err := graphql.ParseFS(tc.fsys, "*.graphql")
+		if err == nil {
+			t.Errorf("got error: nil, want: %v", tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("\n got error: %v\nwant error: %v", got, tc.want)
+		}
+	}
+}
+
+type heroQuery struct {
+	Hero struct {
+		heroFields
+		Friends []heroFields
+		Droid   struct {
+			PrimaryFunction graphql.String
+		} `graphql:"... on Droid"`
+	}
+}
+
+type heroFields struct {
+	ID   graphql.ID
+	Name graphql.String
+}
+
+func TestOperation_Check(t *testing.T) {
+	ops, err := graphql.ParseFS(testOperations, "queries/*.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := ops.Lookup("Hero")
+	if err := op.Check(&heroQuery{}); err != nil {
+		t.Errorf("got error: %v", err)
+	}
+
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{
+			in: &struct {
+				Hero struct {
+					heroFields
+					Height  graphql.Float
+					Friends []heroFields
+					Droid   struct {
+						PrimaryFunction graphql.String
+					} `graphql:"... on Droid"`
+				}
+			}{},
+			want: `operation Hero: struct field Hero.Height: field "hero.height" isn't selected`,
+		},
+		{
+			in: &struct {
+				Hero struct {
+					heroFields
+					Friends []heroFields
+				}
+			}{},
+			want: `operation Hero: selected field "hero.primaryFunction" has no corresponding struct field`,
+		},
+		{
+			in: &struct {
+				Hero struct {
+					heroFields
+					Friends         graphql.String
+					PrimaryFunction graphql.String
+				}
+			}{},
+			want: `operation Hero: struct field Hero.Friends: field "hero.friends" has a selection set, but Go type graphql.String is a scalar`,
+		},
+		{
+			in: &struct {
+				Hero struct {
+					ID              graphql.ID
+					Name            struct{ First graphql.String }
+					Friends         []heroFields
+					PrimaryFunction graphql.String
+				}
+			}{},
+			want: `operation Hero: struct field Hero.Name: field "hero.name" has no selection set, but Go type struct { First graphql.String } needs one`,
+		},
+		{
+			in:   []heroQuery{},
+			want: `cannot check []graphql_test.heroQuery against operation Hero, it must be a struct or pointer to struct`,
+		},
+	}
+	for i, tc := range tests {
+		err := op.Check(tc.in)
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestClient_ExecOperation(t *testing.T) {
+	ops, err := graphql.ParseFS(testOperations, "queries/*.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := ops.Lookup("Hero")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query         string
+			OperationName string
+		}
+		err := json.NewDecoder(req.Body).Decode(&in)
+		if err != nil {
+			t.Error(err)
+		}
+		if in.Query != op.Document() || in.OperationName != "Hero" {
+			t.Errorf("got query %q with operation name %q, want operation Hero", in.Query, in.OperationName)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"hero": {
+			"__typename": "Droid",
+			"id": "2001",
+			"name": "R2-D2",
+			"friends": [{"id": "1000", "name": "Luke Skywalker"}],
+			"primaryFunction": "Astromech"
+		}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q heroQuery
+	err = client.ExecOperation(context.Background(), op, &q, map[string]any{"withFriends": graphql.Boolean(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Hero.Name, graphql.String("R2-D2"); got != want {
+		t.Errorf("got q.Hero.Name: %q, want: %q", got, want)
+	}
+	if got, want := q.Hero.Friends[0].Name, graphql.String("Luke Skywalker"); got != want {
+		t.Errorf("got q.Hero.Friends[0].Name: %q, want: %q", got, want)
+	}
+	if got, want := q.Hero.Droid.PrimaryFunction, graphql.String("Astromech"); got != want {
+		t.Errorf("got q.Hero.Droid.PrimaryFunction: %q, want: %q", got, want)
+	}
+
+	err = client.ExecOperation(context.Background(), op, &q, nil)
+	if got, want := err.Error(), "variable $withFriends is required by operation Hero, but not provided"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	err = client.ExecOperation(context.Background(), op, &q, map[string]any{"withFriends": graphql.Boolean(true), "first": graphql.Int(1)})
+	if got, want := err.Error(), "variable $first isn't defined by operation Hero"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
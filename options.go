@@ -0,0 +1,83 @@
+package graphql
+
+import "github.com/shurcooL/graphql/internal/jsonutil"
+
+// Option configures how GraphQL operations are constructed and executed.
+// Options can be provided to Client.Query, Client.Mutate, Client.Exec,
+// ConstructQuery and ConstructMutation, as well as NewPages and Paginate.
+// Options provided to NewClient apply to all operations of the client.
+type Option func(*options)
+
+// options holds the configuration set by Option values.
+type options struct {
+	indent        string                    // Indentation for pretty-printed documents, or empty for minified ones.
+	operationName string                    // Name of the operation, or empty for an anonymous one.
+	arguments     map[string]map[string]any // Field arguments set by FieldArguments, keyed by response path.
+	hoist         map[string]string         // Types of arguments set by HoistArguments.
+	maxPages      int                       // Maximum number of pages to fetch, or 0 for no limit.
+	backward      bool                      // Whether to paginate backward.
+	scalars       scalars                   // Custom scalars registered with CustomScalar.
+	decode        jsonutil.Options          // Options for decoding response data.
+}
+
+// newOptions returns the configuration set by opts.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Indent makes constructed documents pretty-printed, putting each
+// selection on its own line, indented by indent per nesting level.
+// An empty indent makes constructed documents minified, which is the default.
+func Indent(indent string) Option {
+	return func(o *options) { o.indent = indent }
+}
+
+// OperationName sets the name of the operation. Constructed operations
+// are given the name, e.g., "query Name{...}", instead of being anonymous.
+// With Client.Exec, it selects the operation to execute from a document
+// that has more than one.
+//
+// The name is sent in the "operationName" field of requests.
+func OperationName(name string) Option {
+	return func(o *options) { o.operationName = name }
+}
+
+// LenientDecoding makes response data be decoded leniently: object keys
+// without a corresponding struct field are skipped, along with their values,
+// rather than being an error. This way, fields that a server adds to the
+// response, such as ones of fragments or __typename, don't break decoding.
+// Strict decoding is the default.
+func LenientDecoding() Option {
+	return func(o *options) { o.decode.Lenient = true }
+}
+
+// MaxPages limits the number of pages that NewPages and Paginate fetch to n.
+// A non-positive n means no limit, which is the default.
+func MaxPages(n int) Option {
+	return func(o *options) { o.maxPages = n }
+}
+
+// Backward makes NewPages and Paginate paginate a connection backward,
+// from the start cursor of each page while it has a previous page,
+// rather than forward from the end cursor while it has a next page.
+func Backward() Option {
+	return func(o *options) { o.backward = true }
+}
+
+// DisallowNullStructs makes null be a decoding error when it's the value of
+// a struct that isn't behind a pointer, with the response path of the null
+// in the DecodeError. By default, such structs are zeroed.
+func DisallowNullStructs() Option {
+	return func(o *options) { o.decode.DisallowNullStructs = true }
+}
+
+// RecordNulls makes the response paths of nulls that are decoded into
+// structs that aren't behind a pointer be added to nulls. The structs are
+// zeroed, and nulls tells them apart from empty objects afterwards.
+func RecordNulls(nulls *Nulls) Option {
+	return func(o *options) { o.decode.RecordNull = nulls.add }
+}
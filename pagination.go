@@ -0,0 +1,204 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// PageInfo is the information about a page of a Relay connection,
+// as selected by its pageInfo field. It can be used as the type of
+// that field, or be filled in from it by the function given to NewPages.
+//
+// Specification: https://relay.dev/graphql/connections.htm#sec-PageInfo.
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     string
+	EndCursor       string
+}
+
+// Pages iterates over the pages of a Relay connection, fetching them
+// on demand with a query derived from struct type T. N is the type of
+// the nodes of the connection. Use NewPages to create one. Its use is
+// similar to that of bufio.Scanner:
+//
+//	pages := graphql.NewPages(client, variables, "cursor", func(q *issuesQuery) ([]issue, graphql.PageInfo) {
+//		return q.Repository.Issues.Nodes, q.Repository.Issues.PageInfo
+//	})
+//	for pages.Next(ctx) {
+//		for _, issue := range pages.Nodes() {
+//			// Use issue.
+//		}
+//	}
+//	if err := pages.Err(); err != nil {
+//		// Handle error.
+//	}
+//
+// To iterate over the nodes one at a time, across pages, use NewNodes.
+type Pages[T, N any] struct {
+	c          *Client
+	variables  map[string]any
+	cursor     string                   // Name of the cursor variable.
+	connection func(*T) ([]N, PageInfo) // Locates the connection.
+	opts       options
+
+	page  *T
+	nodes []N
+	pages int  // Number of pages fetched so far.
+	done  bool // Whether there are no more pages to fetch.
+	err   error
+}
+
+// NewPages returns an iterator over the pages of a Relay connection.
+// Each page is fetched by a query derived from T, with the cursor of
+// the previous page as the value of variable cursor, such as "cursor"
+// for $cursor, which is used as the after argument of the connection,
+// or the before argument when paginating backward. connection locates
+// the connection in a fetched page, and returns its nodes and page info.
+//
+// variables are the variables of the query, which aren't modified.
+// If the cursor variable is provided, it's used for the first page.
+// Otherwise, the first page is fetched with a null cursor, and the
+// variable has type String. Cursors of the following pages are
+// *String values.
+//
+// Options other than the ones that configure operations, such as MaxPages
+// and Backward, control the pagination.
+func NewPages[T, N any](c *Client, variables map[string]any, cursor string, connection func(*T) ([]N, PageInfo), opts ...Option) *Pages[T, N] {
+	p := &Pages[T, N]{
+		c:          c,
+		variables:  make(map[string]any, len(variables)+1),
+		cursor:     cursor,
+		connection: connection,
+		opts:       c.options(opts),
+	}
+	for name, value := range variables {
+		p.variables[name] = value
+	}
+	if _, ok := p.variables[cursor]; !ok {
+		p.variables[cursor] = (*String)(nil)
+	}
+	var v T
+	if t := reflect.TypeOf(&v).Elem(); t.Kind() != reflect.Struct {
+		p.err = fmt.Errorf("cannot paginate with query derived from %v, it must be a struct type", t)
+	}
+	return p
+}
+
+// Next fetches the next page, which is then available through Page and Nodes.
+// It returns false when there are no more pages, when the maximum number
+// of pages set by MaxPages is fetched, or when an error occurs, including
+// ctx being done. After Next returns false, Err returns the error, if any.
+func (p *Pages[T, N]) Next(ctx context.Context) bool {
+	if p.err != nil || p.done {
+		return false
+	}
+	if p.opts.maxPages > 0 && p.pages >= p.opts.maxPages {
+		p.done = true
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+	page := new(T)
+	err := p.c.do(ctx, queryOperation, page, p.variables, p.opts)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	nodes, info := p.connection(page)
+	p.page, p.nodes = page, nodes
+	p.pages++
+
+	more, cursor := info.HasNextPage, info.EndCursor
+	if p.opts.backward {
+		more, cursor = info.HasPreviousPage, info.StartCursor
+	}
+	switch {
+	case !more:
+		p.done = true
+	case cursor == "":
+		p.err = fmt.Errorf("page %d of connection has more pages, but no cursor", p.pages)
+		p.done = true
+	default:
+		s := String(cursor)
+		p.variables[p.cursor] = &s
+	}
+	return true
+}
+
+// Page returns the page fetched by the last call to Next.
+// Each page is a new value of T.
+func (p *Pages[T, N]) Page() *T { return p.page }
+
+// Nodes returns the nodes of the connection in the page
+// fetched by the last call to Next.
+func (p *Pages[T, N]) Nodes() []N { return p.nodes }
+
+// Err returns the error that made Next return false, if any.
+func (p *Pages[T, N]) Err() error { return p.err }
+
+// Nodes iterates over the nodes of a Relay connection, one at a time,
+// fetching pages on demand as described by NewPages. Use NewNodes to
+// create one:
+//
+//	nodes := graphql.NewNodes(client, variables, "cursor", func(q *issuesQuery) ([]issue, graphql.PageInfo) {
+//		return q.Repository.Issues.Nodes, q.Repository.Issues.PageInfo
+//	})
+//	for nodes.Next(ctx) {
+//		issue := nodes.Node()
+//		// Use issue.
+//	}
+//	if err := nodes.Err(); err != nil {
+//		// Handle error.
+//	}
+type Nodes[T, N any] struct {
+	pages *Pages[T, N]
+	rest  []N // Nodes of the current page after node.
+	node  N
+}
+
+// NewNodes returns an iterator over the nodes of a Relay connection.
+// The parameters are the same as for NewPages.
+func NewNodes[T, N any](c *Client, variables map[string]any, cursor string, connection func(*T) ([]N, PageInfo), opts ...Option) *Nodes[T, N] {
+	return &Nodes[T, N]{pages: NewPages(c, variables, cursor, connection, opts...)}
+}
+
+// Next advances to the next node, which is then available through Node,
+// fetching the next page if needed. It returns false when there are no
+// more nodes, or when an error occurs. After Next returns false, Err
+// returns the error, if any.
+func (n *Nodes[T, N]) Next(ctx context.Context) bool {
+	for len(n.rest) == 0 {
+		if !n.pages.Next(ctx) {
+			var zero N
+			n.node = zero
+			return false
+		}
+		n.rest = n.pages.Nodes()
+	}
+	n.node, n.rest = n.rest[0], n.rest[1:]
+	return true
+}
+
+// Node returns the node that the last call to Next advanced to.
+func (n *Nodes[T, N]) Node() N { return n.node }
+
+// Err returns the error that made Next return false, if any.
+func (n *Nodes[T, N]) Err() error { return n.pages.Err() }
+
+// Paginate calls fn with each node of a Relay connection, in order,
+// fetching pages as described by NewPages, until there are no more
+// nodes or fn returns an error, which Paginate then returns.
+func Paginate[T, N any](ctx context.Context, c *Client, variables map[string]any, cursor string, connection func(*T) ([]N, PageInfo), fn func(N) error, opts ...Option) error {
+	nodes := NewNodes(c, variables, cursor, connection, opts...)
+	for nodes.Next(ctx) {
+		err := fn(nodes.Node())
+		if err != nil {
+			return err
+		}
+	}
+	return nodes.Err()
+}
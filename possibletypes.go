@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/shurcooL/graphql/internal/fields"
+	"github.com/shurcooL/graphql/internal/language"
+)
+
+// RegisterPossibleTypes registers the Go types that correspond to the
+// possible types of a GraphQL union or interface, which is represented
+// in Go by interface type I. types maps GraphQL type names to values of
+// the Go types, which must be structs or pointers to structs.
+//
+// A struct field of type I is queried with an inline fragment for each
+// possible type, e.g., "search{__typename,... on Issue{title},... on PullRequest{title}}",
+// and it's populated with a value of the Go type that __typename maps to.
+//
+// RegisterPossibleTypes should be called during initialization, before
+// I is used in queries. It panics if I isn't a non-empty interface type,
+// if types is invalid, or if it's called more than once for the same I.
+func RegisterPossibleTypes[I any](types map[string]I) {
+	t := reflect.TypeOf((*I)(nil)).Elem()
+	if t.Kind() != reflect.Interface || t.NumMethod() == 0 {
+		panic(fmt.Errorf("can't register possible types of %v, it must be a non-empty interface type", t))
+	}
+	if len(types) == 0 {
+		panic(fmt.Errorf("can't register possible types of %v, there are none", t))
+	}
+	pts := make([]fields.PossibleType, 0, len(types))
+	for name, v := range types {
+		pt := reflect.TypeOf(v)
+		switch {
+		case !language.IsName(name):
+			panic(fmt.Errorf("can't register possible type %q of %v, it's not a valid GraphQL name", name, t))
+		case pt == nil || fields.Indirect(pt).Kind() != reflect.Struct || pt.Kind() == reflect.Ptr && pt.Elem().Kind() == reflect.Ptr:
+			panic(fmt.Errorf("can't register possible type %q of %v, its Go type %v must be a struct or pointer to struct", name, t, pt))
+		}
+		pts = append(pts, fields.PossibleType{Name: name, Type: pt})
+	}
+	sort.Slice(pts, func(i, j int) bool { return pts[i].Name < pts[j].Name })
+	if !fields.RegisterPossibleTypes(t, pts) {
+		panic(fmt.Errorf("possible types of %v are already registered", t))
+	}
+}
+
+// writePossibleTypes writes a selection set for interface type t, with an
+// inline fragment for each of its possible types. See writeQuery for the
+// meaning of the parameters.
+func (qw *queryWriter) writePossibleTypes(pts []fields.PossibleType, path, responsePath string) error {
+	qw.openSelectionSet()
+	// __typename is needed to decode the response into the right Go type.
+	qw.startSelection()
+	io.WriteString(&qw.buf, "__typename")
+	for _, pt := range pts {
+		qw.startSelection()
+		io.WriteString(&qw.buf, "... on ")
+		io.WriteString(&qw.buf, pt.Name)
+		err := qw.writeQuery(pt.Type, path, responsePath, false)
+		if err != nil {
+			return err
+		}
+	}
+	qw.closeSelectionSet()
+	return nil
+}
@@ -3,61 +3,230 @@ package graphql
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 
-	"github.com/shurcooL/graphql/ident"
+	"github.com/shurcooL/graphql/internal/fields"
+	"github.com/shurcooL/graphql/internal/language"
 )
 
-func constructQuery(v any, variables map[string]any) string {
-	query := query(v)
-	if len(variables) > 0 {
-		return "query(" + queryArguments(variables) + ")" + query
+// ConstructQuery constructs a GraphQL query document with a query derived
+// from q and variables, the same document that Client.Query sends.
+// q should be a pointer to struct that corresponds to the GraphQL schema.
+//
+// By default, the document is minified. Use the Indent option
+// to pretty-print it, e.g., for debugging or golden tests.
+//
+// If the HoistArguments option is used, the document refers to hoisted
+// variables, whose values Client.Query sends along with variables.
+func ConstructQuery(q any, variables map[string]any, opts ...Option) (string, error) {
+	op, err := construct(queryOperation, q, variables, newOptions(opts))
+	return op.query, err
+}
+
+// ConstructMutation constructs a GraphQL mutation document with a mutation derived
+// from m and variables, the same document that Client.Mutate sends.
+// m should be a pointer to struct that corresponds to the GraphQL schema.
+//
+// By default, the document is minified. Use the Indent option
+// to pretty-print it, e.g., for debugging or golden tests.
+//
+// If the HoistArguments option is used, the document refers to hoisted
+// variables, whose values Client.Mutate sends along with variables.
+func ConstructMutation(m any, variables map[string]any, opts ...Option) (string, error) {
+	op, err := construct(mutationOperation, m, variables, newOptions(opts))
+	return op.query, err
+}
+
+func constructQuery(v any, variables map[string]any) (string, error) {
+	op, err := construct(queryOperation, v, variables, options{})
+	return op.query, err
+}
+
+func constructMutation(v any, variables map[string]any) (string, error) {
+	op, err := construct(mutationOperation, v, variables, options{})
+	return op.query, err
+}
+
+// operation is a constructed GraphQL operation.
+type operation struct {
+	query   string
+	hoisted map[string]any // Values of variables hoisted by HoistArguments. It must not be modified.
+}
+
+// variables returns variables merged with the hoisted variables of op.
+func (op operation) variables(variables map[string]any) map[string]any {
+	if len(op.hoisted) == 0 {
+		return variables
+	}
+	merged := make(map[string]any, len(variables)+len(op.hoisted))
+	for k, v := range variables {
+		merged[k] = v
+	}
+	for k, v := range op.hoisted {
+		merged[k] = v
 	}
-	return query
+	return merged
 }
 
-func constructMutation(v any, variables map[string]any) string {
-	query := query(v)
+// queryCache maps a queryKey to the constructed *cachedQuery.
+var queryCache sync.Map
+
+type cachedQuery struct {
+	op  operation
+	err error
+}
+
+// queryKey identifies a constructed operation. The query part depends only
+// on the type of v, and the arguments part depends only on the types of
+// variables, so both can be reused across calls.
+type queryKey struct {
+	op        operationType
+	t         reflect.Type
+	arguments string // Minified arguments string, as returned by queryArguments.
+	indent    string
+	name      string // Operation name set by OperationName.
+	hoist     string // Argument types set by HoistArguments, as returned by hoistKey.
+	scalars   string // Custom scalars registered with CustomScalar, as returned by scalars.key.
+}
+
+// cacheable reports whether the operation constructed with opts
+// depends only on the fields of queryKey, so it can be cached.
+func (opts options) cacheable() bool {
+	return len(opts.arguments) == 0
+}
+
+// construct constructs an operation of type op, with a query
+// derived from v. It's safe for concurrent use.
+func construct(op operationType, v any, variables map[string]any, opts options) (operation, error) {
+	if _, dynamic := v.(Selections); dynamic || !opts.cacheable() {
+		return constructUncached(op, v, variables, opts)
+	}
+	var arguments string
 	if len(variables) > 0 {
-		return "mutation(" + queryArguments(variables) + ")" + query
+		arguments = queryArguments(variables, opts.scalars)
+	}
+	key := queryKey{op: op, t: reflect.TypeOf(v), arguments: arguments, indent: opts.indent, name: opts.operationName, hoist: opts.hoistKey(), scalars: opts.scalars.key()}
+	if q, ok := queryCache.Load(key); ok {
+		return q.(*cachedQuery).op, q.(*cachedQuery).err
 	}
-	return "mutation" + query
+	o, err := constructUncached(op, v, variables, opts)
+	queryCache.Store(key, &cachedQuery{op: o, err: err})
+	return o, err
 }
 
-// queryArguments constructs a minified arguments string for variables.
+// constructUncached constructs an operation like construct,
+// without using queryCache.
+func constructUncached(op operationType, v any, variables map[string]any, opts options) (operation, error) {
+	if opts.operationName != "" && !language.IsName(opts.operationName) {
+		return operation{}, fmt.Errorf("invalid operation name %q", opts.operationName)
+	}
+	qw := &queryWriter{indent: opts.indent, variables: variables, arguments: opts.arguments, scalars: opts.scalars}
+	err := qw.setHoist(opts.hoist)
+	if err != nil {
+		return operation{}, err
+	}
+	err = qw.query(v)
+	if err != nil {
+		return operation{}, err
+	}
+
+	var buf bytes.Buffer
+	if op == mutationOperation {
+		io.WriteString(&buf, "mutation")
+	} else if len(variables) > 0 || len(qw.hoisted) > 0 || opts.operationName != "" {
+		io.WriteString(&buf, "query")
+	}
+	if opts.operationName != "" {
+		io.WriteString(&buf, " ")
+		io.WriteString(&buf, opts.operationName)
+	}
+	if len(variables) > 0 || len(qw.hoisted) > 0 {
+		if qw.indent != "" && opts.operationName == "" {
+			io.WriteString(&buf, " ")
+		}
+		io.WriteString(&buf, "(")
+		writeArguments(&buf, variables, qw.hoistedTypes, qw.scalars, qw.indent != "")
+		io.WriteString(&buf, ")")
+	}
+	if qw.indent != "" && buf.Len() > 0 {
+		io.WriteString(&buf, " ")
+	}
+	buf.Write(qw.buf.Bytes())
+	return operation{query: buf.String(), hoisted: qw.hoisted}, nil
+}
+
+// queryArguments constructs a minified arguments string for variables,
+// with types of custom scalars in scalars.
 //
 // E.g., map[string]any{"a": Int(123), "b": NewBoolean(true)} -> "$a:Int!$b:Boolean".
-func queryArguments(variables map[string]any) string {
+func queryArguments(variables map[string]any, scalars scalars) string {
+	var buf bytes.Buffer
+	writeArguments(&buf, variables, nil, scalars, false)
+	return buf.String()
+}
+
+// writeArguments writes an arguments string for variables to w.
+// The types of hoisted variables, if any, are given by hoistedTypes,
+// and Go types of custom scalars are mapped to them by scalars.
+// If pretty is false, the arguments string is minified.
+//
+// E.g., map[string]any{"a": Int(123), "b": NewBoolean(true)} -> "$a:Int!$b:Boolean",
+// or "$a: Int!, $b: Boolean" if pretty is true.
+func writeArguments(w io.Writer, variables map[string]any, hoistedTypes map[string]language.Type, scalars scalars, pretty bool) {
 	// Sort keys in order to produce deterministic output for testing purposes.
 	// TODO: If tests can be made to work with non-deterministic output, then no need to sort.
-	keys := make([]string, 0, len(variables))
+	keys := make([]string, 0, len(variables)+len(hoistedTypes))
 	for k := range variables {
 		keys = append(keys, k)
 	}
+	for k := range hoistedTypes {
+		keys = append(keys, k)
+	}
 	sort.Strings(keys)
 
-	var buf bytes.Buffer
-	for _, k := range keys {
-		io.WriteString(&buf, "$")
-		io.WriteString(&buf, k)
-		io.WriteString(&buf, ":")
-		writeArgumentType(&buf, reflect.TypeOf(variables[k]), true)
-		// Don't insert a comma here.
+	for i, k := range keys {
+		if pretty && i != 0 {
+			io.WriteString(w, ", ")
+		}
+		io.WriteString(w, "$")
+		io.WriteString(w, k)
+		io.WriteString(w, ":")
+		if pretty {
+			io.WriteString(w, " ")
+		}
+		if t, ok := hoistedTypes[k]; ok {
+			language.WriteType(w, t)
+		} else {
+			writeArgumentType(w, reflect.TypeOf(variables[k]), true, scalars)
+		}
+		// Don't insert a comma here when minifying.
 		// Commas in GraphQL are insignificant, and we want minified output.
 		// See https://spec.graphql.org/October2021/#sec-Insignificant-Commas.
 	}
-	return buf.String()
 }
 
 // writeArgumentType writes a minified GraphQL type for t to w.
 // value indicates whether t is a value (required) type or pointer (optional) type.
 // If value is true, then "!" is written at the end of t.
-func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
+// Go types of custom scalars are mapped to their GraphQL types by scalars.
+func writeArgumentType(w io.Writer, t reflect.Type, value bool, scalars scalars) {
+	if s, ok := scalars[t]; ok {
+		// Custom scalar. E.g., "DateTime". If its Go type is a pointer,
+		// such as *big.Int, it's an optional type, like other pointers.
+		io.WriteString(w, s.name)
+		if value && t.Kind() != reflect.Ptr {
+			io.WriteString(w, "!")
+		}
+		return
+	}
 	if t.Kind() == reflect.Ptr {
 		// Pointer is an optional type, so no "!" at the end of the pointer's underlying type.
-		writeArgumentType(w, t.Elem(), false)
+		writeArgumentType(w, t.Elem(), false, scalars)
 		return
 	}
 
@@ -65,7 +234,7 @@ func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
 	case reflect.Slice, reflect.Array:
 		// List. E.g., "[Int]".
 		io.WriteString(w, "[")
-		writeArgumentType(w, t.Elem(), true)
+		writeArgumentType(w, t.Elem(), true, scalars)
 		io.WriteString(w, "]")
 	default:
 		// Named type. E.g., "Int".
@@ -82,50 +251,358 @@ func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
 	}
 }
 
+// queryWriter writes GraphQL queries derived from Go types,
+// either minified or pretty-printed.
+type queryWriter struct {
+	buf    bytes.Buffer
+	indent string // Indentation for each nesting level, or empty for minified output.
+	depth  int    // Current nesting level of selection sets.
+	first  bool   // Whether the next selection is the first one in its selection set.
+
+	variables map[string]any // Variables provided by the caller.
+	scalars   scalars        // Custom scalars registered with CustomScalar.
+
+	arguments map[string]map[string]any // Field arguments set by FieldArguments, keyed by response path.
+	matched   map[string]bool           // Response paths in arguments that matched a field.
+
+	hoist        map[string]language.Type // Types of arguments to hoist, keyed by response path and argument name.
+	hoistFields  map[string]bool          // Response paths of fields with arguments to hoist.
+	hoistMatched map[string]bool          // Keys in hoist that matched a field argument.
+	hoisted      map[string]any           // Values of hoisted variables.
+	hoistedTypes map[string]language.Type // Types of hoisted variables.
+
+	fragments []fragmentDefinition // Named fragments used in the query, in order of first use.
+}
+
 // query uses writeQuery to recursively construct
-// a minified query string from the provided struct v.
+// a query string from the provided struct v.
+// It returns an error if v can't be represented as a GraphQL query.
 //
 // E.g., struct{Foo Int, BarBaz *Boolean} -> "{foo,barBaz}".
-func query(v any) string {
-	var buf bytes.Buffer
-	writeQuery(&buf, reflect.TypeOf(v), false)
-	return buf.String()
+func (qw *queryWriter) query(v any) error {
+	if sels, ok := v.(Selections); ok {
+		err := qw.writeSelections(sels, "")
+		if err != nil {
+			return err
+		}
+	} else {
+		t := reflect.TypeOf(v)
+		if t == nil || fields.Indirect(t).Kind() != reflect.Struct {
+			return fmt.Errorf("cannot construct query from %T, it must be a struct or pointer to struct", v)
+		}
+		err := qw.writeQuery(t, "", "", false)
+		if err != nil {
+			return err
+		}
+	}
+	err := qw.writeFragmentDefinitions()
+	if err != nil {
+		return err
+	}
+	for path := range qw.arguments {
+		if !qw.matched[path] {
+			return fmt.Errorf("FieldArguments path %q doesn't match any field", path)
+		}
+	}
+	for key := range qw.hoist {
+		if !qw.hoistMatched[key] {
+			return fmt.Errorf("HoistArguments argument %q doesn't match any field argument", key)
+		}
+	}
+	return nil
 }
 
-// writeQuery writes a minified query for t to w.
+// writeQuery writes a query for t.
 // If inline is true, the struct fields of t are inlined into parent struct.
-func writeQuery(w io.Writer, t reflect.Type, inline bool) {
+// path is the Go field path of t, used in errors, e.g., "Repository.Issue".
+// responsePath is the GraphQL response key path of t, e.g., "repository.issue".
+func (qw *queryWriter) writeQuery(t reflect.Type, path, responsePath string, inline bool) error {
+	if _, ok := qw.scalars[t]; ok {
+		// A custom scalar. Don't expand it.
+		return nil
+	}
+	if fields.IsAppender(t) {
+		elem, err := fields.AppenderElem(t)
+		if err != nil {
+			return fieldError(path, "%v", err)
+		}
+		return qw.writeQuery(elem, path, responsePath, false)
+	}
 	switch t.Kind() {
-	case reflect.Ptr, reflect.Slice:
-		writeQuery(w, t.Elem(), false)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return qw.writeQuery(t.Elem(), path, responsePath, false)
 	case reflect.Struct:
 		// If the type implements json.Unmarshaler, it's a scalar. Don't expand it.
 		if reflect.PtrTo(t).Implements(jsonUnmarshaler) {
-			return
+			return nil
+		}
+		if fields.IsNamedFragment(t) {
+			return qw.writeFragmentSpread(t, path, inline)
+		}
+		return qw.writeSelectionSet(t, path, responsePath, inline)
+	case reflect.Map:
+		// A map with string keys, like the empty interface, can hold a JSON scalar.
+		if t.Key().Kind() != reflect.String {
+			return fieldError(path, "map type %v can't be represented in a GraphQL query, only maps with string keys can", t)
+		}
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fieldError(path, "%v type %v can't be represented in a GraphQL query", t.Kind(), t)
+	case reflect.Interface:
+		if pts := fields.PossibleTypes(t); pts != nil {
+			return qw.writePossibleTypes(pts, path, responsePath)
+		}
+		// An empty interface, such as ID, can hold any scalar.
+		if t.NumMethod() != 0 {
+			return fieldError(path, "interface type %v can't be represented in a GraphQL query without possible types registered with RegisterPossibleTypes, only the empty interface can be used for a scalar", t)
+		}
+	}
+	return nil
+}
+
+// writeSelectionSet writes the selection set of struct type t.
+// See writeQuery for the meaning of the parameters.
+func (qw *queryWriter) writeSelectionSet(t reflect.Type, path, responsePath string, inline bool) error {
+	fs, err := fields.Of(t)
+	if err != nil {
+		return err
+	}
+	if len(fs) == 0 {
+		return fieldError(path, "struct type %v has no fields, but a GraphQL selection set can't be empty", t)
+	}
+	if !inline {
+		qw.openSelectionSet()
+		if abstract, hasTypename := typenameUsage(t); abstract && !hasTypename {
+			// Request __typename, so that the response can be decoded
+			// into the inline fragment that matches the concrete type.
+			qw.startSelection()
+			io.WriteString(&qw.buf, "__typename")
+		}
+	}
+	for _, f := range fs {
+		sf := t.Field(f.Index)
+		fieldPath := sf.Name
+		if path != "" {
+			fieldPath = path + "." + sf.Name
 		}
-		if !inline {
-			io.WriteString(w, "{")
+		err := checkField(f, sf, fieldPath)
+		if err != nil {
+			return err
 		}
-		for i := 0; i < t.NumField(); i++ {
-			if i != 0 {
-				io.WriteString(w, ",")
+		if f.Selection != nil {
+			err := checkSelection(f.Selection, qw.variables)
+			if err != nil {
+				return fieldError(fieldPath, "%v", err)
 			}
-			f := t.Field(i)
-			value, ok := f.Tag.Lookup("graphql")
-			inlineField := f.Anonymous && !ok
-			if !inlineField {
-				if ok {
-					io.WriteString(w, value)
-				} else {
-					io.WriteString(w, ident.ParseMixedCaps(f.Name).ToLowerCamelCase())
-				}
+		}
+		fieldResponsePath := responsePath
+		if f.Name != "" && responsePath != "" {
+			fieldResponsePath = responsePath + "." + f.Name
+		} else if f.Name != "" {
+			fieldResponsePath = f.Name
+		}
+		if !f.Inline {
+			qw.startSelection()
+			err := qw.writeSelection(f, fieldPath, fieldResponsePath)
+			if err != nil {
+				return err
 			}
-			writeQuery(w, f.Type, inlineField)
 		}
-		if !inline {
-			io.WriteString(w, "}")
+		if sel, ok := f.Selection.(*language.Field); ok && sel.SelectionSet != nil {
+			// The selection set is specified by the tag, rather than derived from the type.
+			elem, err := fields.ListElem(sf.Type)
+			if err != nil {
+				return fieldError(fieldPath, "%v", err)
+			}
+			if !fields.CapturesValue(elem) {
+				return fieldError(fieldPath, "graphql struct tag has a selection set, but Go type %v isn't a map, empty interface or json.Unmarshaler", sf.Type)
+			}
+			err = qw.writeTagSelectionSet(sel.SelectionSet, fieldPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = qw.writeQuery(sf.Type, fieldPath, fieldResponsePath, f.Inline)
+		if err != nil {
+			return err
 		}
 	}
+	if !inline {
+		qw.closeSelectionSet()
+	}
+	return nil
+}
+
+// writeTagSelectionSet writes the selection set sels,
+// which is specified by the graphql struct tag of the field at Go field path.
+func (qw *queryWriter) writeTagSelectionSet(sels []language.Selection, path string) error {
+	qw.openSelectionSet()
+	for _, sel := range sels {
+		err := checkSelection(sel, qw.variables)
+		if err != nil {
+			return fieldError(path, "%v", err)
+		}
+		qw.startSelection()
+		language.WriteSelection(&qw.buf, sel, qw.indent != "")
+		var selectionSet []language.Selection
+		switch sel := sel.(type) {
+		case *language.Field:
+			selectionSet = sel.SelectionSet
+		case *language.InlineFragment:
+			selectionSet = sel.SelectionSet
+		}
+		if selectionSet != nil {
+			err := qw.writeTagSelectionSet(selectionSet, path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	qw.closeSelectionSet()
+	return nil
+}
+
+// typenameUsage reports whether the selection set of struct type t contains
+// inline fragments with type conditions, or more than one embedded named
+// fragment, and whether it selects __typename. A lone named fragment doesn't
+// need __typename, because fragments are skipped only in favor of a sibling.
+func typenameUsage(t reflect.Type) (abstract, hasTypename bool) {
+	inline, named, hasTypename := typeConditions(t)
+	return inline > 0 || inline+named > 1, hasTypename
+}
+
+// typeConditions counts the inline fragments and embedded named fragments
+// with type conditions in the selection set of struct type t, and reports
+// whether it selects __typename. Fields of embedded structs are part of
+// the same selection set.
+func typeConditions(t reflect.Type) (inline, named int, hasTypename bool) {
+	fs, err := fields.Of(t)
+	if err != nil {
+		// The error is reported when writing the fields.
+		return 0, 0, false
+	}
+	for _, f := range fs {
+		switch {
+		case f.Fragment && f.TypeCondition != "":
+			inline++
+		case f.TypeCondition != "":
+			named++
+		case f.Name == "__typename":
+			hasTypename = true
+		case f.Inline && t.Field(f.Index).Type.Kind() == reflect.Struct:
+			i, n, h := typeConditions(t.Field(f.Index).Type)
+			inline, named, hasTypename = inline+i, named+n, hasTypename || h
+		}
+	}
+	return inline, named, hasTypename
+}
+
+// writeSelection writes the field or inline fragment f, without its selection set.
+func (qw *queryWriter) writeSelection(f fields.Field, path, responsePath string) error {
+	_, ok := qw.arguments[responsePath]
+	if f.Fragment || !ok && !qw.hoistFields[responsePath] {
+		if f.Selection == nil {
+			io.WriteString(&qw.buf, f.Name)
+			return nil
+		}
+		language.WriteSelection(&qw.buf, f.Selection, qw.indent != "")
+		return nil
+	}
+	sel, isField := f.Selection.(*language.Field)
+	if !isField {
+		sel = &language.Field{Name: f.Name}
+	}
+	sel, err := qw.applyArguments(sel, responsePath)
+	if err != nil {
+		return fieldError(path, "%v", err)
+	}
+	language.WriteSelection(&qw.buf, sel, qw.indent != "")
+	return nil
+}
+
+// applyArguments returns field sel, at responsePath, with the arguments
+// set by FieldArguments added to it, and the ones set by HoistArguments hoisted.
+func (qw *queryWriter) applyArguments(sel *language.Field, responsePath string) (*language.Field, error) {
+	args, ok := qw.arguments[responsePath]
+	if ok {
+		if qw.matched == nil {
+			qw.matched = make(map[string]bool)
+		}
+		qw.matched[responsePath] = true
+		var err error
+		sel, err = withArguments(sel, args, qw.scalars)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if qw.hoistFields[responsePath] {
+		var err error
+		sel, err = qw.hoistArguments(sel, responsePath, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+// openSelectionSet writes the start of a selection set.
+func (qw *queryWriter) openSelectionSet() {
+	if qw.indent != "" && qw.depth > 0 {
+		io.WriteString(&qw.buf, " ")
+	}
+	io.WriteString(&qw.buf, "{")
+	qw.depth++
+	qw.first = true
+}
+
+// closeSelectionSet writes the end of a selection set.
+func (qw *queryWriter) closeSelectionSet() {
+	qw.depth--
+	if qw.indent != "" {
+		io.WriteString(&qw.buf, "\n")
+		io.WriteString(&qw.buf, strings.Repeat(qw.indent, qw.depth))
+	}
+	io.WriteString(&qw.buf, "}")
+	qw.first = false
+}
+
+// startSelection writes the separator that goes before a selection.
+func (qw *queryWriter) startSelection() {
+	switch {
+	case qw.indent != "":
+		io.WriteString(&qw.buf, "\n")
+		io.WriteString(&qw.buf, strings.Repeat(qw.indent, qw.depth))
+	case !qw.first:
+		io.WriteString(&qw.buf, ",")
+	}
+	qw.first = false
+}
+
+// checkField checks that struct field sf, described by f, can be used
+// in a GraphQL query data structure.
+func checkField(f fields.Field, sf reflect.StructField, path string) error {
+	isStruct := sf.Type.Kind() == reflect.Struct && !reflect.PtrTo(sf.Type).Implements(jsonUnmarshaler)
+	switch {
+	case !f.Exported && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct):
+		// Exported fields of embedded unexported structs can still be set,
+		// so those are okay.
+		return fieldError(path, "unexported field can't be populated with the response")
+	case f.Inline && sf.Type.Kind() == reflect.Ptr:
+		return fieldError(path, "embedded pointer type %v can't be inlined, only embedded struct types can", sf.Type)
+	case f.Inline && !isStruct:
+		return fieldError(path, "embedded type %v can't be inlined, only embedded struct types can; use a graphql struct tag to select it as a field", sf.Type)
+	case f.Fragment && !isStruct:
+		return fieldError(path, "inline fragment must have a struct type, not %v", sf.Type)
+	}
+	return nil
+}
+
+// fieldError returns an error about the struct field at Go field path.
+func fieldError(path string, format string, a ...any) error {
+	if path == "" {
+		return fmt.Errorf(format, a...)
+	}
+	return fmt.Errorf("struct field %s: %s", path, fmt.Sprintf(format, a...))
 }
 
 var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
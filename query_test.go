@@ -1,7 +1,10 @@
 package graphql
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/url"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -53,7 +56,7 @@ func TestConstructQuery(t *testing.T) {
 					} `graphql:"issue(number:1)"`
 				} `graphql:"repository(owner:\"shurcooL-test\"name:\"test-repo\")"`
 			}{},
-			want: `{repository(owner:"shurcooL-test"name:"test-repo"){databaseId,url,issue(number:1){comments(first:1after:"Y3Vyc29yOjE5NTE4NDI1Ng=="){edges{node{body,author{login},editor{login}},cursor}}}}}`,
+			want: `{repository(owner:"shurcooL-test",name:"test-repo"){databaseId,url,issue(number:1){comments(first:1,after:"Y3Vyc29yOjE5NTE4NDI1Ng=="){edges{node{body,author{login},editor{login}},cursor}}}}}`,
 		},
 		{
 			inV: func() any {
@@ -87,7 +90,7 @@ func TestConstructQuery(t *testing.T) {
 					} `graphql:"repository(owner:\"shurcooL-test\"name:\"test-repo\")"`
 				}{}
 			}(),
-			want: `{repository(owner:"shurcooL-test"name:"test-repo"){databaseId,url,issue(number:1){comments(first:1){edges{node{databaseId,author{login,avatarUrl,url},publishedAt,lastEditedAt,editor{login,avatarUrl,url},body,viewerCanUpdate},cursor}}}}}`,
+			want: `{repository(owner:"shurcooL-test",name:"test-repo"){databaseId,url,issue(number:1){comments(first:1){edges{node{databaseId,author{login,avatarUrl,url},publishedAt,lastEditedAt,editor{login,avatarUrl,url},body,viewerCanUpdate},cursor}}}}}`,
 		},
 		{
 			inV: func() any {
@@ -140,7 +143,7 @@ func TestConstructQuery(t *testing.T) {
 					} `graphql:"repository(owner:\"shurcooL-test\"name:\"test-repo\")"`
 				}{}
 			}(),
-			want: `{repository(owner:"shurcooL-test"name:"test-repo"){issue(number:1){author{login,avatarUrl(size:72),url},publishedAt,lastEditedAt,editor{login,avatarUrl(size:72),url},body,reactionGroups{content,users{totalCount},viewerHasReacted},viewerCanUpdate,comments(first:1){nodes{databaseId,author{login,avatarUrl(size:72),url},publishedAt,lastEditedAt,editor{login,avatarUrl(size:72),url},body,reactionGroups{content,users{totalCount},viewerHasReacted},viewerCanUpdate},pageInfo{endCursor,hasNextPage}}}}}`,
+			want: `{repository(owner:"shurcooL-test",name:"test-repo"){issue(number:1){author{login,avatarUrl(size:72),url},publishedAt,lastEditedAt,editor{login,avatarUrl(size:72),url},body,reactionGroups{content,users{totalCount},viewerHasReacted},viewerCanUpdate,comments(first:1){nodes{databaseId,author{login,avatarUrl(size:72),url},publishedAt,lastEditedAt,editor{login,avatarUrl(size:72),url},body,reactionGroups{content,users{totalCount},viewerHasReacted},viewerCanUpdate},pageInfo{endCursor,hasNextPage}}}}}`,
 		},
 		{
 			inV: struct {
@@ -150,7 +153,7 @@ func TestConstructQuery(t *testing.T) {
 					} `graphql:"issue(number: 1)"`
 				} `graphql:"repository(owner:\"shurcooL-test\"name:\"test-repo\")"`
 			}{},
-			want: `{repository(owner:"shurcooL-test"name:"test-repo"){issue(number: 1){body}}}`,
+			want: `{repository(owner:"shurcooL-test",name:"test-repo"){issue(number:1){body}}}`,
 		},
 		{
 			inV: struct {
@@ -165,7 +168,7 @@ func TestConstructQuery(t *testing.T) {
 				"repositoryName":  String("test-repo"),
 				"issueNumber":     Int(1),
 			},
-			want: `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner: $repositoryOwner, name: $repositoryName){issue(number: $issueNumber){body}}}`,
+			want: `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner:$repositoryOwner,name:$repositoryName){issue(number:$issueNumber){body}}}`,
 		},
 		{
 			inV: struct {
@@ -186,7 +189,7 @@ func TestConstructQuery(t *testing.T) {
 				"repositoryName":  String("test-repo"),
 				"issueNumber":     Int(1),
 			},
-			want: `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner: $repositoryOwner, name: $repositoryName){issue(number: $issueNumber){reactionGroups{users(first:10){nodes{login}}}}}}`,
+			want: `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner:$repositoryOwner,name:$repositoryName){issue(number:$issueNumber){reactionGroups{users(first:10){nodes{login}}}}}}`,
 		},
 		// Embedded structs without graphql tag should be inlined in query.
 		{
@@ -214,7 +217,7 @@ func TestConstructQuery(t *testing.T) {
 					}
 				}{}
 			}(),
-			want: `{actor{login,avatarUrl,url},createdAt,... on IssueComment{body},currentTitle,previousTitle,label{name,color}}`,
+			want: `{__typename,actor{login,avatarUrl,url},createdAt,... on IssueComment{body},currentTitle,previousTitle,label{name,color}}`,
 		},
 		{
 			inV: struct {
@@ -229,8 +232,10 @@ func TestConstructQuery(t *testing.T) {
 		},
 	}
 	for _, tc := range tests {
-		got := constructQuery(tc.inV, tc.inVariables)
-		if got != tc.want {
+		got, err := constructQuery(tc.inV, tc.inVariables)
+		if err != nil {
+			t.Error(err)
+		} else if got != tc.want {
 			t.Errorf("\ngot:  %q\nwant: %q\n", got, tc.want)
 		}
 	}
@@ -264,8 +269,10 @@ func TestConstructMutation(t *testing.T) {
 		},
 	}
 	for _, tc := range tests {
-		got := constructMutation(tc.inV, tc.inVariables)
-		if got != tc.want {
+		got, err := constructMutation(tc.inV, tc.inVariables)
+		if err != nil {
+			t.Error(err)
+		} else if got != tc.want {
 			t.Errorf("\ngot:  %q\nwant: %q\n", got, tc.want)
 		}
 	}
@@ -315,7 +322,7 @@ func TestQueryArguments(t *testing.T) {
 		},
 	}
 	for i, tc := range tests {
-		got := queryArguments(tc.in)
+		got := queryArguments(tc.in, nil)
 		if got != tc.want {
 			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
 		}
@@ -365,3 +372,1061 @@ type AddReactionInput struct {
 	// A unique identifier for the client performing the mutation. (Optional.)
 	ClientMutationID *String `json:"clientMutationId,omitempty"`
 }
+
+// Test that queries constructed from the same type, but with
+// variables of different types, aren't mixed up by queryCache.
+func TestConstructQuery_cache(t *testing.T) {
+	type query struct {
+		Node struct {
+			ID ID
+		} `graphql:"node(id:$id)"`
+	}
+	tests := []struct {
+		inVariables map[string]any
+		want        string
+	}{
+		{map[string]any{"id": ID("someID")}, `query($id:ID!){node(id:$id){id}}`},
+		{map[string]any{"id": NewID("someID")}, `query($id:ID){node(id:$id){id}}`},
+		{map[string]any{"id": ID("anotherID")}, `query($id:ID!){node(id:$id){id}}`},
+		{nil, `{node(id:$id){id}}`},
+	}
+	for i, tc := range tests {
+		if got, err := constructQuery(query{}, tc.inVariables); err != nil {
+			t.Errorf("test case %d: %v", i, err)
+		} else if got != tc.want {
+			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
+		}
+		if got, err := constructMutation(query{}, tc.inVariables); err != nil {
+			t.Errorf("test case %d: %v", i, err)
+		} else if got == tc.want {
+			t.Errorf("test case %d: got mutation equal to query %q", i, got)
+		}
+	}
+}
+
+func TestConstructQuery_invalidTag(t *testing.T) {
+	type issue struct {
+		Body String
+	}
+	type repository struct {
+		Issue issue `graphql:"issue(number: $issueNumber"`
+	}
+	var q struct {
+		Repository repository `graphql:"repository(owner: $repositoryOwner, name: $repositoryName)"`
+	}
+	_, err := constructQuery(q, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), `invalid graphql struct tag "issue(number: $issueNumber" on struct field graphql.repository.Issue: syntax error at column 27: expected name, found end of input`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestConstructQuery_tagComment(t *testing.T) {
+	// A comment would hide the rest of the document, since tags are written as is.
+	var q struct {
+		Viewer struct {
+			Login String `graphql:"login # The user's login."`
+			Name  String
+		}
+	}
+	_, err := constructQuery(q, nil)
+	if got, want := fmt.Sprint(err), `invalid graphql struct tag "login # The user's login." on struct field Login: syntax error at column 7: comments aren't allowed in graphql struct tags`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestConstructQuery_error(t *testing.T) {
+	type event struct {
+		CreatedAt DateTime
+	}
+	tests := []struct {
+		inV  any
+		want string
+	}{
+		{
+			inV:  map[string]any{},
+			want: "cannot construct query from map[string]interface {}, it must be a struct or pointer to struct",
+		},
+		{
+			inV:  nil,
+			want: "cannot construct query from <nil>, it must be a struct or pointer to struct",
+		},
+		{
+			inV: struct {
+				Viewer struct {
+					Followers map[int]Int
+				}
+			}{},
+			want: "struct field Viewer.Followers: map type map[int]graphql.Int can't be represented in a GraphQL query, only maps with string keys can",
+		},
+		{
+			inV: struct {
+				Repository struct {
+					Issues []struct {
+						OnUpdate func()
+					}
+				}
+			}{},
+			want: "struct field Repository.Issues.OnUpdate: func type func() can't be represented in a GraphQL query",
+		},
+		{
+			inV: struct {
+				Events chan event
+			}{},
+			want: "struct field Events: chan type chan graphql.event can't be represented in a GraphQL query",
+		},
+		{
+			inV: struct {
+				Viewer struct {
+					Name interface{ String() string }
+				}
+			}{},
+			want: "struct field Viewer.Name: interface type interface { String() string } can't be represented in a GraphQL query without possible types registered with RegisterPossibleTypes, only the empty interface can be used for a scalar",
+		},
+		{
+			inV: struct {
+				Viewer struct {
+					login String
+				}
+			}{},
+			want: "struct field Viewer.login: unexported field can't be populated with the response",
+		},
+		{
+			inV: struct {
+				*event
+			}{},
+			want: "struct field event: unexported field can't be populated with the response",
+		},
+		{
+			inV: struct {
+				*AddReactionInput
+			}{},
+			want: "struct field AddReactionInput: embedded pointer type *graphql.AddReactionInput can't be inlined, only embedded struct types can",
+		},
+		{
+			inV: struct {
+				String
+			}{},
+			want: "struct field String: embedded type graphql.String can't be inlined, only embedded struct types can; use a graphql struct tag to select it as a field",
+		},
+		{
+			inV: struct {
+				Node struct {
+					Login String `graphql:"... on User"`
+				}
+			}{},
+			want: "struct field Node.Login: inline fragment must have a struct type, not graphql.String",
+		},
+		{
+			inV: struct {
+				Node struct {
+					User *struct{ Login String } `graphql:"... on User"`
+				}
+			}{},
+			want: "struct field Node.User: inline fragment must have a struct type, not *struct { Login graphql.String }",
+		},
+		{
+			inV: struct {
+				Viewer struct{}
+			}{},
+			want: "struct field Viewer: struct type struct {} has no fields, but a GraphQL selection set can't be empty",
+		},
+	}
+	for i, tc := range tests {
+		_, err := constructQuery(tc.inV, nil)
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestConstructQuery_indent(t *testing.T) {
+	type event struct {
+		Actor struct {
+			Login String
+		}
+		CreatedAt DateTime
+	}
+	var q struct {
+		Repository struct {
+			Timeline struct {
+				Nodes []struct {
+					event
+					IssueComment struct {
+						Body String
+					} `graphql:"... on IssueComment"`
+				}
+			} `graphql:"timeline(first:10)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]any{
+		"owner": String("shurcooL-test"),
+		"name":  NewString("test-repo"),
+	}
+	got, err := ConstructQuery(&q, variables, Indent("\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `query ($name: String, $owner: String!) {
+	repository(owner: $owner, name: $name) {
+		timeline(first: 10) {
+			nodes {
+				__typename
+				actor {
+					login
+				}
+				createdAt
+				... on IssueComment {
+					body
+				}
+			}
+		}
+	}
+}`
+	if got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Test that the minified query is unaffected by the pretty-printed one.
+	got, err = ConstructQuery(&q, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query($name:String$owner:String!){repository(owner:$owner,name:$name){timeline(first:10){nodes{__typename,actor{login},createdAt,... on IssueComment{body}}}}}`; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestConstructMutation_indent(t *testing.T) {
+	var m struct {
+		AddReaction struct {
+			Subject struct {
+				ID ID
+			}
+		} `graphql:"addReaction(input: {subjectId: \"MDU6SXNzdWUyMzE1MjcyNzk=\", content: HOORAY})"`
+	}
+	got, err := ConstructMutation(&m, nil, Indent("  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `mutation {
+  addReaction(input: {subjectId: "MDU6SXNzdWUyMzE1MjcyNzk=", content: HOORAY}) {
+    subject {
+      id
+    }
+  }
+}`
+	if got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConstructQuery_fieldArguments(t *testing.T) {
+	type input struct {
+		Text     string  `json:"text"`
+		Priority *Int    `json:"priority,omitempty"`
+		Labels   []Enum  `json:"labels"`
+		Skipped  string  `json:"-"`
+		Note     *String `json:"note"`
+	}
+	var q struct {
+		Repository struct {
+			Issue struct {
+				Body String
+			}
+			Issues struct {
+				Nodes []struct {
+					Title    String
+					Comments struct {
+						TotalCount Int
+					} `graphql:"allComments: comments(first: 10)"`
+				}
+			} `graphql:"issues(first: 10) @include(if: true)"`
+		}
+		Search struct {
+			IssueCount Int
+		}
+	}
+	got, err := ConstructQuery(&q, nil,
+		FieldArguments("repository", map[string]any{"owner": "shurcooL-\"test\"", "name": String("test-repo")}),
+		FieldArguments("repository.issue", map[string]any{"number": 1}),
+		FieldArguments("repository.issues", map[string]any{"states": []Enum{"OPEN", "CLOSED"}, "orderBy": map[string]any{"field": Enum("CREATED_AT"), "direction": Enum("DESC")}}),
+		FieldArguments("repository.issues.nodes.allComments", map[string]any{"after": nil}),
+		FieldArguments("search", map[string]any{"query": "is:open\nlabel:bug", "filter": input{Text: "x", Labels: []Enum{"A"}}, "ratio": 0.5, "since": time.Unix(1498709521, 0).UTC()}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{repository(name:"test-repo",owner:"shurcooL-\"test\""){issue(number:1){body},issues(first:10,orderBy:{direction:DESC,field:CREATED_AT},states:[OPEN,CLOSED])@include(if:true){nodes{title,allComments:comments(first:10,after:null){totalCount}}}},search(filter:{text:"x",labels:[A],note:null},query:"is:open\nlabel:bug",ratio:0.5,since:"2017-06-29T04:12:01Z"){issueCount}}`
+	if got != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+
+	// Arguments are pretty-printed too.
+	got, err = ConstructQuery(&q.Repository.Issue, nil, Indent(" "), FieldArguments("body", map[string]any{"format": Enum("HTML"), "limit": []int{1, 2}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "{\n body(format: HTML, limit: [1, 2])\n}"
+	if got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestConstructQuery_fieldArgumentsError(t *testing.T) {
+	type query struct {
+		Repository struct {
+			Issue struct {
+				Body String
+			} `graphql:"issue(number: 1)"`
+		}
+	}
+	tests := []struct {
+		in   Option
+		want string
+	}{
+		{
+			in:   FieldArguments("repository.issues", map[string]any{"first": 1}),
+			want: `FieldArguments path "repository.issues" doesn't match any field`,
+		},
+		{
+			in:   FieldArguments("repository.issue", map[string]any{"number": 1}),
+			want: `struct field Repository.Issue: argument "number" is specified both in graphql struct tag and FieldArguments`,
+		},
+		{
+			in:   FieldArguments("repository", map[string]any{"owner": func() {}}),
+			want: `struct field Repository: argument "owner": unsupported type func()`,
+		},
+		{
+			in:   FieldArguments("repository", map[string]any{"state": Enum("not an enum")}),
+			want: `struct field Repository: argument "state": invalid enum value "not an enum"`,
+		},
+		{
+			in:   FieldArguments("repository", map[string]any{"$owner": "x"}),
+			want: `struct field Repository: invalid argument name "$owner"`,
+		},
+	}
+	for i, tc := range tests {
+		_, err := ConstructQuery(query{}, nil, tc.in)
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestConstructQuery_hoistArguments(t *testing.T) {
+	type issue struct {
+		Body String
+	}
+	hoist := HoistArguments(map[string]string{
+		"repository.owner":        "String!",
+		"repository.name":         "String!",
+		"repository.issue.number": "Int!",
+		"repository.issue.labels": "[String!]",
+	})
+	q1 := struct {
+		Repository struct {
+			Issue issue `graphql:"issue(number: 1, labels: [\"bug\"])"`
+		} `graphql:"repository(owner: \"shurcooL\", name: $name, first: 10)"`
+	}{}
+	q2 := struct {
+		Repository struct {
+			Issue issue `graphql:"issue(number: 2, labels: null)"`
+		} `graphql:"repository(owner: \"octocat\", name: $name, first: 10)"`
+	}{}
+	variables := map[string]any{
+		"name": String("test-repo"),
+		"v1":   Int(0), // Variables provided by the caller take precedence.
+	}
+	const want = `query($name:String!$v1:Int!$v2:String!$v3:Int!$v4:[String!]){repository(owner:$v2,name:$name,first:10){issue(number:$v3,labels:$v4){body}}}`
+
+	op, err := construct(queryOperation, q1, variables, newOptions([]Option{hoist}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.query != want {
+		t.Errorf("\ngot:  %s\nwant: %s", op.query, want)
+	}
+	if got, want := op.variables(variables), map[string]any{"name": String("test-repo"), "v1": Int(0), "v2": "shurcooL", "v3": json.Number("1"), "v4": []any{"bug"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables: %#v, want: %#v", got, want)
+	}
+
+	// A different struct type with different argument values
+	// should result in the same document.
+	op, err = construct(queryOperation, q2, variables, newOptions([]Option{hoist}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.query != want {
+		t.Errorf("\ngot:  %s\nwant: %s", op.query, want)
+	}
+	if got, want := op.variables(variables), map[string]any{"name": String("test-repo"), "v1": Int(0), "v2": "octocat", "v3": json.Number("2"), "v4": nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables: %#v, want: %#v", got, want)
+	}
+}
+
+func TestConstructQuery_hoistFieldArguments(t *testing.T) {
+	var q struct {
+		Search struct {
+			IssueCount Int
+		}
+	}
+	for _, query := range []string{"is:open", "is:closed"} {
+		op, err := construct(queryOperation, &q, nil, newOptions([]Option{
+			FieldArguments("search", map[string]any{"query": query, "type": Enum("ISSUE")}),
+			HoistArguments(map[string]string{"search.query": "String!"}),
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := op.query, `query($v1:String!){search(query:$v1,type:ISSUE){issueCount}}`; got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+		if got, want := op.variables(nil), map[string]any{"v1": query}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got variables: %#v, want: %#v", got, want)
+		}
+	}
+}
+
+func TestConstructQuery_hoistArgumentsError(t *testing.T) {
+	var q struct {
+		Node struct {
+			ID ID
+		} `graphql:"node(id: \"x\", filter: {owner: $owner})"`
+	}
+	tests := []struct {
+		in   map[string]string
+		want string
+	}{
+		{
+			in:   map[string]string{"node.ids": "ID!"},
+			want: `HoistArguments argument "node.ids" doesn't match any field argument`,
+		},
+		{
+			in:   map[string]string{"node": "ID!"},
+			want: `HoistArguments argument "node" must be a field path followed by a dot and the argument name`,
+		},
+		{
+			in:   map[string]string{"node.id": "[ID!"},
+			want: `HoistArguments argument "node.id" has invalid type "[ID!": syntax error at column 5: expected "]", found end of input`,
+		},
+		{
+			in:   map[string]string{"node.filter": "NodeFilter"},
+			want: `struct field Node: can't hoist argument "filter": value contains variable $owner`,
+		},
+	}
+	for i, tc := range tests {
+		_, err := ConstructQuery(&q, nil, HoistArguments(tc.in))
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+type (
+	userFields struct {
+		Login     String
+		AvatarURL URI `graphql:"avatarUrl(size: 72)"`
+		Status    struct {
+			statusFields
+		}
+	}
+	statusFields struct {
+		Message String
+	}
+	badFragment    struct{ Login String }
+	otherUserField struct{ Name String }
+)
+
+func (userFields) GraphQLFragment() string     { return "UserFields on User" }
+func (statusFields) GraphQLFragment() string   { return "StatusFields on UserStatus" }
+func (badFragment) GraphQLFragment() string    { return "UserFields" }
+func (otherUserField) GraphQLFragment() string { return "UserFields on Actor" }
+
+func TestConstructQuery_namedFragments(t *testing.T) {
+	var q struct {
+		Viewer     userFields
+		Repository struct {
+			Owner struct {
+				userFields
+				URL URI
+			}
+			Issues struct {
+				Nodes []struct {
+					Author *userFields
+					Editor struct {
+						User userFields `graphql:"... on User"`
+					}
+				}
+			} `graphql:"issues(first: 10)"`
+		} `graphql:"repository(owner: \"shurcooL\", name: \"graphql\")"`
+	}
+	got, err := ConstructQuery(&q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{viewer{...UserFields},repository(owner:"shurcooL",name:"graphql"){owner{...UserFields,url},issues(first:10){nodes{author{...UserFields},editor{__typename,... on User{...UserFields}}}}}}` +
+		`fragment UserFields on User{login,avatarUrl(size:72),status{...StatusFields}}fragment StatusFields on UserStatus{message}`
+	if got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+
+	got, err = ConstructQuery(&struct{ Viewer userFields }{}, nil, Indent("\t"), FieldArguments("...UserFields.avatarUrl", map[string]any{"scale": 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{
+	viewer {
+		...UserFields
+	}
+}
+
+fragment UserFields on User {
+	login
+	avatarUrl(size: 72, scale: 2)
+	status {
+		...StatusFields
+	}
+}
+
+fragment StatusFields on UserStatus {
+	message
+}`
+	if got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConstructQuery_namedFragmentsError(t *testing.T) {
+	tests := []struct {
+		in   any
+		opts []Option
+		want string
+	}{
+		{
+			in:   &struct{ Viewer badFragment }{},
+			want: `struct field Viewer: invalid named fragment "UserFields" returned by graphql.badFragment.GraphQLFragment, want "Name on Type"`,
+		},
+		{
+			in: &struct {
+				Viewer userFields
+				Actor  otherUserField
+			}{},
+			want: `struct field Actor: named fragment "UserFields" is used by both graphql.userFields and graphql.otherUserField`,
+		},
+		{
+			in:   &struct{ Viewer userFields }{},
+			opts: []Option{FieldArguments("viewer.avatarUrl", map[string]any{"scale": 2})},
+			want: `FieldArguments path "viewer.avatarUrl" doesn't match any field`,
+		},
+	}
+	for i, tc := range tests {
+		_, err := ConstructQuery(tc.in, nil, tc.opts...)
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestConstructQuery_typename(t *testing.T) {
+	type droidFragment struct {
+		PrimaryFunction String
+	}
+	type characterFields struct {
+		Name          String
+		droidFragment `graphql:"... on Droid"`
+	}
+	tests := []struct {
+		in        any
+		variables map[string]any
+		want      string
+	}{
+		{
+			in: struct {
+				Hero struct {
+					characterFields
+				}
+			}{},
+			want: `{hero{__typename,name,... on Droid{primaryFunction}}}`,
+		},
+		{
+			in: struct {
+				Hero struct {
+					Typename String `graphql:"__typename"`
+					characterFields
+				}
+			}{},
+			want: `{hero{__typename,name,... on Droid{primaryFunction}}}`,
+		},
+		{
+			in: struct {
+				Hero struct {
+					Name    String
+					Details struct {
+						Name String
+					} `graphql:"... @include(if: $details)"`
+				}
+			}{},
+			variables: map[string]any{"details": Boolean(true)},
+			want:      `query($details:Boolean!){hero{name,...@include(if:$details){name}}}`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := constructQuery(tc.in, tc.variables)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("\ngot:  %q\nwant: %q", got, tc.want)
+		}
+	}
+}
+
+func TestTypename(t *testing.T) {
+	type typename struct {
+		Typename String `graphql:"__typename"`
+	}
+	var hero struct {
+		typename
+		Name String
+	}
+	if got := Typename(&hero); got != "" {
+		t.Errorf("got Typename: %q, want empty", got)
+	}
+	hero.Typename = "Droid"
+	if got, want := Typename(&hero), "Droid"; got != want {
+		t.Errorf("got Typename: %q, want: %q", got, want)
+	}
+	if got := Typename(struct{ Name String }{}); got != "" {
+		t.Errorf("got Typename: %q, want empty", got)
+	}
+	if got := Typename(42); got != "" {
+		t.Errorf("got Typename: %q, want empty", got)
+	}
+}
+
+type (
+	searchResult     interface{ isSearchResult() }
+	issueResult      struct{ Title String }
+	repositoryResult struct {
+		NameWithOwner String
+		Owner         struct{ userFields }
+	}
+)
+
+func (issueResult) isSearchResult()       {}
+func (*repositoryResult) isSearchResult() {}
+
+func init() {
+	RegisterPossibleTypes(map[string]searchResult{
+		"Repository": (*repositoryResult)(nil),
+		"Issue":      issueResult{},
+	})
+}
+
+func TestConstructQuery_possibleTypes(t *testing.T) {
+	var q struct {
+		Search struct {
+			Nodes []searchResult
+		} `graphql:"search(query: \"graphql\", type: ISSUE, first: 10)"`
+	}
+	got, err := constructQuery(&q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{search(query:"graphql",type:ISSUE,first:10){nodes{__typename,... on Issue{title},... on Repository{nameWithOwner,owner{...UserFields}}}}}` +
+		`fragment UserFields on User{login,avatarUrl(size:72),status{...StatusFields}}fragment StatusFields on UserStatus{message}`
+	if got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRegisterPossibleTypes_panic(t *testing.T) {
+	type stringer interface{ String() string }
+	tests := []struct {
+		register func()
+		want     string
+	}{
+		{
+			register: func() { RegisterPossibleTypes(map[string]any{"Issue": issueResult{}}) },
+			want:     "can't register possible types of interface {}, it must be a non-empty interface type",
+		},
+		{
+			register: func() { RegisterPossibleTypes(map[string]stringer{}) },
+			want:     "can't register possible types of graphql.stringer, there are none",
+		},
+		{
+			register: func() { RegisterPossibleTypes(map[string]stringer{"Date Time": DateTime{}}) },
+			want:     `can't register possible type "Date Time" of graphql.stringer, it's not a valid GraphQL name`,
+		},
+		{
+			register: func() { RegisterPossibleTypes(map[string]stringer{"Issue": nil}) },
+			want:     `can't register possible type "Issue" of graphql.stringer, its Go type <nil> must be a struct or pointer to struct`,
+		},
+		{
+			register: func() { RegisterPossibleTypes(map[string]searchResult{"Issue": issueResult{}}) },
+			want:     "possible types of graphql.searchResult are already registered",
+		},
+	}
+	for i, tc := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Errorf("test case %d: didn't panic, want: %v", i, tc.want)
+					return
+				}
+				if got := fmt.Sprint(r); got != tc.want {
+					t.Errorf("test case %d:\n got panic: %v\nwant panic: %v", i, got, tc.want)
+				}
+			}()
+			tc.register()
+		}()
+	}
+}
+
+func TestConstructQuery_directives(t *testing.T) {
+	var q struct {
+		Hero struct {
+			Name    String `graphql:"name @include(if: $withName)"`
+			Friends []struct {
+				Name String
+			} `graphql:"friends(first: $first) @skip(if: $noFriends) @cached(ttl: 60)"`
+			Droid struct {
+				PrimaryFunction String
+			} `graphql:"... on Droid @include(if: $withDroid)"`
+		}
+	}
+	variables := map[string]any{
+		"withName":  Boolean(true),
+		"first":     Int(3),
+		"noFriends": Boolean(false),
+		"withDroid": Boolean(true),
+	}
+	got, err := constructQuery(&q, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `query($first:Int!$noFriends:Boolean!$withDroid:Boolean!$withName:Boolean!){hero{__typename,name@include(if:$withName),friends(first:$first)@skip(if:$noFriends)@cached(ttl:60){name},... on Droid@include(if:$withDroid){primaryFunction}}}`
+	if got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestConstructQuery_directivesError(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{
+			in: struct {
+				Name String `graphql:"name @include(if: $withName)"`
+			}{},
+			want: "struct field Name: variable $withName is used, but not provided in variables",
+		},
+		{
+			in: struct {
+				Search struct {
+					IssueCount Int
+				} `graphql:"search(first: $first) @cached(keys: [{label: $label}])"`
+			}{},
+			want: "struct field Search: variable $label is used, but not provided in variables",
+		},
+		{
+			in: struct {
+				Name String `graphql:"name @include"`
+			}{},
+			want: `struct field Name: directive @include must have a single "if" argument`,
+		},
+		{
+			in: struct {
+				Name String `graphql:"name @skip(if: true, unless: false)"`
+			}{},
+			want: `struct field Name: directive @skip must have a single "if" argument`,
+		},
+		{
+			in: struct {
+				Droid struct {
+					PrimaryFunction String
+				} `graphql:"... on Droid @skip(if: true) @skip(if: false)"`
+			}{},
+			want: "struct field Droid: directive @skip can't be used more than once",
+		},
+	}
+	for i, tc := range tests {
+		_, err := constructQuery(tc.in, map[string]any{"first": Int(1)})
+		if err == nil {
+			t.Errorf("test case %d: got error: nil, want: %v", i, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestConstructQuery_operationName(t *testing.T) {
+	var q struct {
+		Node struct {
+			ID ID
+		} `graphql:"node(id: $id)"`
+	}
+	variables := map[string]any{"id": ID("MDEy")}
+	tests := []struct {
+		construct func(any, map[string]any, ...Option) (string, error)
+		opts      []Option
+		want      string
+	}{
+		{ConstructQuery, nil, `query($id:ID!){node(id:$id){id}}`},
+		{ConstructQuery, []Option{OperationName("Node")}, `query Node($id:ID!){node(id:$id){id}}`},
+		{ConstructMutation, []Option{OperationName("Node")}, `mutation Node($id:ID!){node(id:$id){id}}`},
+		{ConstructQuery, []Option{OperationName("Node"), Indent(" ")}, "query Node($id: ID!) {\n node(id: $id) {\n  id\n }\n}"},
+	}
+	for i, tc := range tests {
+		got, err := tc.construct(&q, variables, tc.opts...)
+		if err != nil {
+			t.Errorf("test case %d: %v", i, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("test case %d:\n got: %q\nwant: %q", i, got, tc.want)
+		}
+	}
+
+	if got, err := ConstructQuery(&struct{ Viewer struct{ Login String } }{}, nil, OperationName("Viewer")); err != nil {
+		t.Error(err)
+	} else if want := `query Viewer{viewer{login}}`; got != want {
+		t.Errorf("\n got: %q\nwant: %q", got, want)
+	}
+
+	_, err := ConstructQuery(&q, variables, OperationName("my-query"))
+	if got, want := fmt.Sprint(err), `invalid operation name "my-query"`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestConstructQuery_selections(t *testing.T) {
+	q := Selections{
+		Select("viewer",
+			Select("login"),
+			Select("avatarUrl").Args(map[string]any{"size": 72}).As("smallAvatar"),
+		),
+		Select("repository",
+			Select("issue",
+				Select("title"),
+				Select("author",
+					On("User", Select("name")),
+				),
+			).Args(map[string]any{"number": Variable("number")}),
+		).Args(map[string]any{"owner": "octocat", "name": "Hello-World"}),
+	}
+	variables := map[string]any{"number": Int(1)}
+	got, err := ConstructQuery(q, variables, FieldArguments("viewer.login", map[string]any{"format": Enum("UPPER")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query($number:Int!){viewer{login(format:UPPER),smallAvatar:avatarUrl(size:72)},repository(name:"Hello-World",owner:"octocat"){issue(number:$number){title,author{... on User{name}}}}}`; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+
+	got, err = ConstructQuery(q, variables, Indent("\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query ($number: Int!) {
+	viewer {
+		login
+		smallAvatar: avatarUrl(size: 72)
+	}
+	repository(name: "Hello-World", owner: "octocat") {
+		issue(number: $number) {
+			title
+			author {
+				... on User {
+					name
+				}
+			}
+		}
+	}
+}`; got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConstructQuery_selectionsError(t *testing.T) {
+	tests := []struct {
+		sels Selections
+		want string
+	}{
+		{
+			sels: nil,
+			want: "selection set can't be empty",
+		},
+		{
+			sels: Selections{Select("viewer", Select("1login"))},
+			want: `selection viewer: invalid field name "1login"`,
+		},
+		{
+			sels: Selections{Select("viewer").As("a-b")},
+			want: `invalid alias "a-b" of field "viewer"`,
+		},
+		{
+			sels: Selections{On("", Select("login"))},
+			want: `invalid type condition ""`,
+		},
+		{
+			sels: Selections{Select("node").Args(map[string]any{"id": Variable("$id")})},
+			want: `selection node: argument "id": invalid variable name "$id"`,
+		},
+	}
+	for i, tc := range tests {
+		_, err := ConstructQuery(tc.sels, nil)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, err, tc.want)
+		}
+	}
+}
+
+func TestConstructQuery_capturedValues(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login    String
+			Status   map[string]any   `graphql:"status{emoji,message,expiresAt}"`
+			Settings json.RawMessage  `graphql:"settings { theme, flags(first: $first) { name } ... on OrgSettings { sso } }"`
+			Labels   []map[string]any `graphql:"labels{name}"`
+			Metadata any
+			Theme    map[string]any // A JSON scalar, without a selection set.
+		}
+	}
+	variables := map[string]any{"first": Int(10)}
+	got, err := ConstructQuery(&q, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query($first:Int!){viewer{login,status{emoji,message,expiresAt},settings{theme,flags(first:$first){name},... on OrgSettings{sso}},labels{name},metadata,theme}}`; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+
+	got, err = ConstructQuery(&q, variables, Indent("  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query ($first: Int!) {
+  viewer {
+    login
+    status {
+      emoji
+      message
+      expiresAt
+    }
+    settings {
+      theme
+      flags(first: $first) {
+        name
+      }
+      ... on OrgSettings {
+        sso
+      }
+    }
+    labels {
+      name
+    }
+    metadata
+    theme
+  }
+}`; got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	tests := []struct {
+		inV  any
+		want string
+	}{
+		{
+			inV: struct {
+				Viewer struct {
+					Login String
+				} `graphql:"viewer{login}"`
+			}{},
+			want: "struct field Viewer: graphql struct tag has a selection set, but Go type struct { Login graphql.String } isn't a map, empty interface or json.Unmarshaler",
+		},
+		{
+			inV: struct {
+				Status map[string]any `graphql:"status{emoji @include(if: $withEmoji)}"`
+			}{},
+			want: "struct field Status: variable $withEmoji is used, but not provided in variables",
+		},
+	}
+	for i, tc := range tests {
+		_, err := ConstructQuery(tc.inV, nil)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("test case %d:\n got error: %v\nwant error: %v", i, err, tc.want)
+		}
+	}
+}
+
+// issueList is a custom collection of issues.
+type issueList struct {
+	issues []*struct {
+		Number Int
+		Title  String
+	}
+}
+
+func (l *issueList) AppendGraphQL() any {
+	issue := new(struct {
+		Number Int
+		Title  String
+	})
+	l.issues = append(l.issues, issue)
+	return issue
+}
+
+// badList is a custom collection whose AppendGraphQL doesn't return a pointer.
+type badList struct{}
+
+func (*badList) AppendGraphQL() any { return 0 }
+
+func TestConstructQuery_collections(t *testing.T) {
+	var q struct {
+		Repository struct {
+			Issues struct {
+				Nodes issueList
+			} `graphql:"issues(first:2)"`
+			Languages [3]struct {
+				Name String
+			} `graphql:"languages(first:3)"`
+		}
+	}
+	got, err := ConstructQuery(&q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{repository{issues(first:2){nodes{number,title}},languages(first:3){name}}}`; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+
+	_, err = ConstructQuery(&struct{ Nodes badList }{}, nil)
+	if got, want := fmt.Sprint(err), "struct field Nodes: graphql.badList.AppendGraphQL must return a non-nil pointer to an element, not int"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
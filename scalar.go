@@ -1,5 +1,16 @@
 package graphql
 
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/graphql/internal/language"
+)
+
 // Note: These custom types are meant to be used in queries for now.
 // But the plan is to switch to using native Go types (string, int, bool, time.Time, etc.).
 // See https://github.com/shurcooL/githubv4/issues/9 for details.
@@ -49,3 +60,204 @@ func NewInt(v Int) *Int { return &v }
 
 // NewString is a helper to make a new *String.
 func NewString(v String) *String { return &v }
+
+// CustomScalar registers Go type T as the representation of the custom
+// GraphQL scalar type name, e.g., "BigInt", for the operations that the
+// option applies to. Provided to NewClient, it applies to all operations
+// of the client. This way, types that can't be given json.Marshaler and
+// json.Unmarshaler methods, such as ones from other packages, can be used
+// as scalars without wrapper types.
+//
+// Values of T are scalars in queries, variables of type T are declared
+// with type name, e.g., "$n:BigInt!", and pointers to T as optional.
+//
+// encode converts a value of T to a value that encodes to its JSON form,
+// e.g., a string. It's used for variables and field arguments.
+// decode converts the JSON value of the scalar in response data, as decoded
+// by encoding/json with UseNumber, e.g., a string or json.Number, to a value
+// of T. Null is decoded as the zero value of T, without calling decode.
+//
+// CustomScalar panics if name isn't a valid GraphQL name.
+func CustomScalar[T any](name string, encode func(T) (any, error), decode func(value any) (T, error)) Option {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if !language.IsName(name) {
+		panic(fmt.Errorf("can't register custom scalar %q of %v, it's not a valid GraphQL name", name, t))
+	}
+	s := &scalar{
+		name: name,
+		encode: func(v reflect.Value) (any, error) {
+			return encode(v.Interface().(T))
+		},
+		decode: func(value any, v reflect.Value) error {
+			x, err := decode(value)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(&x).Elem())
+			return nil
+		},
+	}
+	return func(o *options) {
+		if o.scalars == nil {
+			o.scalars = make(scalars)
+			o.decode.Scalars = make(map[reflect.Type]func(any, reflect.Value) error)
+		}
+		o.scalars[t] = s
+		o.decode.Scalars[t] = s.decode
+	}
+}
+
+// TimeScalar registers time.Time as the representation of the custom
+// GraphQL scalar type name, e.g., "DateTime", whose values are strings
+// formatted according to layout, as in time.Format. See CustomScalar.
+func TimeScalar(name, layout string) Option {
+	return CustomScalar(name,
+		func(t time.Time) (any, error) { return t.Format(layout), nil },
+		func(value any) (time.Time, error) {
+			s, ok := value.(string)
+			if !ok {
+				return time.Time{}, fmt.Errorf("%s value must be a string, not %T", name, value)
+			}
+			return time.Parse(layout, s)
+		},
+	)
+}
+
+// scalar is a custom scalar registered with CustomScalar.
+type scalar struct {
+	name   string                                 // GraphQL name of the scalar type.
+	encode func(v reflect.Value) (any, error)     // Converts v to a value that encodes to its JSON form.
+	decode func(value any, v reflect.Value) error // Sets v to the JSON value of the scalar.
+}
+
+// scalars maps Go types to the custom scalars they represent.
+type scalars map[reflect.Type]*scalar
+
+// key returns a string that identifies the custom scalars in s.
+func (s scalars) key() string {
+	if len(s) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(s))
+	for t, sc := range s {
+		keys = append(keys, sc.name+"="+t.PkgPath()+"."+t.String()+";")
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "")
+}
+
+// encodeVariables returns variables with the values of custom scalars in s
+// encoded by them, at any depth. Values of other types are left to be encoded
+// by encoding/json.
+func (s scalars) encodeVariables(variables map[string]any) (map[string]any, error) {
+	if len(s) == 0 || len(variables) == 0 {
+		return variables, nil
+	}
+	encoded := make(map[string]any, len(variables))
+	for name, value := range variables {
+		var err error
+		encoded[name], err = s.encode(reflect.ValueOf(value))
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %v", name, err)
+		}
+	}
+	return encoded, nil
+}
+
+// encode returns a value that encodes to the same JSON as v, except that
+// values of custom scalars in s are encoded by them. Parts of v that can't
+// contain such values are returned as is.
+func (s scalars) encode(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if sc, ok := s[v.Type()]; ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		return sc.encode(v)
+	}
+	if !s.mayContain(v.Type(), make(map[reflect.Type]bool)) {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return s.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		l := make([]any, v.Len())
+		for i := range l {
+			var err error
+			l[i], err = s.encode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]any, v.Len())
+		for _, k := range v.MapKeys() {
+			var err error
+			m[k.String()], err = s.encode(v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case reflect.Struct:
+		m := make(map[string]any)
+		err := objectFields(v, func(sf reflect.StructField, name string, fv reflect.Value) error {
+			value, err := s.encode(fv)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", sf.Name, err)
+			}
+			m[name] = value
+			return nil
+		})
+		return m, err
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// mayContain reports whether values of type t may contain values of
+// custom scalars in s. Values of types that encode themselves, such as ones
+// that implement json.Marshaler or encoding.TextMarshaler, don't.
+// visiting holds the types being checked, to stop at recursive types.
+func (s scalars) mayContain(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if _, ok := s[t]; ok {
+		return true
+	}
+	if visiting[t] || t.Implements(jsonMarshaler) || t.Implements(textMarshaler) ||
+		t.Kind() != reflect.Ptr && (reflect.PtrTo(t).Implements(jsonMarshaler) || reflect.PtrTo(t).Implements(textMarshaler)) {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Interface:
+		// It depends on the dynamic value.
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return s.mayContain(t.Elem(), visiting)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && s.mayContain(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if s.mayContain(t.Field(i).Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()